@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/lukeshay/gocden/pkg/cmds"
 	"github.com/lukeshay/gocden/pkg/cmds/build"
+	"github.com/lukeshay/gocden/pkg/cmds/check"
+	"github.com/lukeshay/gocden/pkg/cmds/configcmd"
 	"github.com/lukeshay/gocden/pkg/cmds/dev"
+	"github.com/lukeshay/gocden/pkg/cmds/docs"
+	"github.com/lukeshay/gocden/pkg/cmds/scaffold"
 	"github.com/lukeshay/gocden/pkg/cmds/serve"
 	"github.com/lukeshay/gocden/pkg/config"
 )
@@ -27,10 +36,6 @@ var (
 	})()
 )
 
-func getConfigFromCliContext(c *cli.Context) config.Config {
-	return c.Context.Value(config.ConfigPath).(config.Config)
-}
-
 func configureLog(cli *cli.Context) {
 	writers := []io.Writer{logFile}
 
@@ -45,13 +50,24 @@ func configureLog(cli *cli.Context) {
 	)
 }
 
+var jobsFlag = &cli.IntFlag{
+	Name:        "jobs",
+	Aliases:     []string{"j"},
+	Value:       runtime.NumCPU(),
+	DefaultText: "number of CPUs",
+	Usage:       "number of pages to write at the same time",
+}
+
 func main() {
 	interruptChannel := make(chan os.Signal, 1)
-	signal.Notify(interruptChannel, os.Interrupt)
+	signal.Notify(interruptChannel, os.Interrupt, syscall.SIGTERM)
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		<-interruptChannel
 		cancel()
+
+		// a second interrupt stops gocden without waiting for it to cancel
+		signal.Stop(interruptChannel)
 	}()
 
 	cwd, err := os.Getwd()
@@ -61,29 +77,51 @@ func main() {
 		os.Exit(1)
 	}
 
-	app := &cli.App{
-		Name:        "gocden",
-		Description: "generate simple documentation",
+	app := newApp(cwd)
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		if errors.Is(err, context.Canceled) {
+			fmt.Println("Canceled")
+
+			os.Exit(130)
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// newApp returns the gocden command, with cwd as the default directory of the
+// site.
+func newApp(cwd string) *cli.App {
+	return &cli.App{
+		Name:  "gocden",
+		Usage: "generate simple documentation",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
-				Name: "verbose",
+				Name:  "verbose",
+				Usage: "also write logs to stderr",
 			},
 			&cli.StringFlag{
-				Name:  "cwd",
-				Value: cwd,
+				Name:        "cwd",
+				Value:       cwd,
+				DefaultText: "current directory",
+				Usage:       "directory of the site",
+			},
+			&cli.StringFlag{
+				Name:        "config",
+				DefaultText: "nearest gocden.toml",
+				Usage:       "path of the config file, relative to the current directory",
+			},
+			&cli.StringFlag{
+				Name:    "env",
+				EnvVars: []string{"GOCDEN_ENV"},
+				Usage:   "environment whose config file overrides the config, such as staging for gocden.staging.toml",
 			},
 		},
 		Before: func(c *cli.Context) error {
 			configureLog(c)
 
-			conf, err := config.ReadAndValidateOrCreate(cwd)
-			if err != nil {
-				fmt.Println("Could not read or validate config", err.Error())
-				return err
-			}
-
-			c.Context = context.WithValue(c.Context, config.ConfigPath, conf)
-
 			return nil
 		},
 		Commands: []*cli.Command{
@@ -97,31 +135,166 @@ func main() {
 			},
 			{
 				Name:        "init",
-				Description: "Creates a configuration file in the current directory if one does not exist",
+				Description: "Creates a configuration file in the directory of the site if one does not exist",
 				Action: func(c *cli.Context) error {
+					if _, err := config.Create(cmds.GetCwdFlag(c)); err != nil {
+						return fmt.Errorf("Error creating config: %w", err)
+					}
+
 					fmt.Printf("A configuration has been generated.\n\nYou are now ready to build your documentation! Get started by creating a markdown file in `./docs/`.\n")
 					return nil
 				},
 			},
+			{
+				Name:        "new",
+				Description: "Creates a new site or page",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "site",
+						Description: "Creates a new site in a directory from a starter",
+						ArgsUsage:   "<dir>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "starter",
+								Value: "minimal",
+								Usage: "starter of the site, one of: " + strings.Join(scaffold.Starters(), ", "),
+							},
+							&cli.StringFlag{
+								Name:        "name",
+								DefaultText: "name of the directory",
+								Usage:       "name of the site",
+							},
+						},
+						Before: cmds.ParseTrailingFlags,
+						Action: scaffold.Site,
+					},
+					{
+						Name:        "page",
+						Description: "Creates a new page with the next free ordering prefix",
+						ArgsUsage:   "<path>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "title",
+								DefaultText: "from the file name",
+								Usage:       "title of the page",
+							},
+							&cli.StringFlag{
+								Name:  "section",
+								Usage: "nav section of the page",
+							},
+						},
+						Before: func(c *cli.Context) error {
+							if err := cmds.ParseTrailingFlags(c); err != nil {
+								return err
+							}
+
+							return cmds.LoadConfig(c)
+						},
+						Action: scaffold.Page,
+					},
+				},
+			},
 			{
 				Name:        "build",
 				Description: "Builds the documentation using the configuration",
+				Flags:       []cli.Flag{jobsFlag},
+				Before:      cmds.LoadConfig,
 				Action:      build.Build,
 			},
+			{
+				Name:        "check",
+				Description: "Checks the pages for problems without building them",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: "text",
+						Usage: "format of the findings, one of: text, json, sarif",
+					},
+					&cli.StringFlag{
+						Name:        "fail-on",
+						DefaultText: "check.fail_on of the config",
+						Usage:       "lowest severity of the findings that fail the check, one of: error, warning, info, never",
+					},
+				},
+				Before: cmds.LoadConfig,
+				Action: check.Check,
+			},
 			{
 				Name:        "serve",
 				Description: "Serves the built documentation",
+				Before:      cmds.LoadConfig,
 				Action:      serve.Serve,
 			},
 			{
 				Name:        "dev",
 				Description: "Starts a development server and watches for changes",
+				Flags:       []cli.Flag{jobsFlag},
+				Before:      cmds.LoadConfig,
 				Action:      dev.Dev,
 			},
+			{
+				Name:        "config",
+				Description: "Inspects the configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "print",
+						Description: "Prints the resolved configuration and where each value comes from",
+						Before:      cmds.LoadConfig,
+						Action:      configcmd.Print,
+					},
+				},
+			},
+			{
+				Name:        "docs",
+				Description: "Generates documentation pages",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "cli",
+						Description: "Writes a reference page for each gocden command",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "out",
+								DefaultText: "<src>/cli",
+								Usage:       "directory to write the pages to",
+							},
+							&cli.StringFlag{
+								Name:  "section",
+								Value: "CLI Reference",
+								Usage: "nav section of the pages",
+							},
+						},
+						Before: cmds.LoadConfig,
+						Action: docs.CLI,
+					},
+					{
+						Name:        "config",
+						Description: "Writes the reference page and JSON Schema of the configuration file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:        "out",
+								DefaultText: "<src>/configuration.md",
+								Usage:       "file to write the page to",
+							},
+							&cli.StringFlag{
+								Name:  "schema",
+								Value: "gocden.schema.json",
+								Usage: "file to write the JSON Schema to",
+							},
+							&cli.StringFlag{
+								Name:  "title",
+								Value: "Configuration",
+								Usage: "title of the page",
+							},
+							&cli.StringFlag{
+								Name:  "section",
+								Usage: "nav section of the page",
+							},
+						},
+						Before: cmds.LoadConfig,
+						Action: docs.Config,
+					},
+				},
+			},
 		},
 	}
-
-	if err := app.RunContext(ctx, os.Args); err != nil {
-		panic(err)
-	}
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWithFlagsAfterArguments(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := newApp(dir).Run([]string{"gocden", "new", "site", "mysite", "--starter", "api", "--name", "My Site"}); err != nil {
+		t.Fatalf("new site: %v", err)
+	}
+
+	site := filepath.Join(dir, "mysite")
+
+	conf, err := os.ReadFile(filepath.Join(site, "gocden.toml"))
+	if err != nil {
+		t.Fatalf("new site did not create a config: %v", err)
+	}
+	if !strings.Contains(string(conf), "My Site") {
+		t.Errorf("config does not have the name given with --name:\n%s", conf)
+	}
+
+	if err := newApp(site).Run([]string{"gocden", "new", "page", "guides/foo.md", "--section", "X", "--title", "Y"}); err != nil {
+		t.Fatalf("new page: %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(site, "*", "*guides", "*foo.md"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("new page did not create guides/foo.md: %v %v", matches, err)
+	}
+
+	page, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`title: "Y"`, "section: X"} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("page does not have %q:\n%s", want, page)
+		}
+	}
+}
+
+func TestBuildWithoutSocial(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"gocden.yaml":   "name: Docs\nbuild:\n  src: docs\n  out: dist\n",
+		"docs/index.md": "---\ntitle: Home\n---\n\n# Home\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := newApp(dir).Run([]string{"gocden", "build"}); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "dist", "index.html")); err != nil {
+		t.Errorf("build did not write index.html: %v", err)
+	}
+}
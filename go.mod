@@ -13,6 +13,8 @@ require (
 	github.com/pelletier/go-toml/v2 v2.1.1
 	github.com/urfave/cli/v2 v2.27.1
 	github.com/yuin/goldmark v1.7.0
+	github.com/yuin/goldmark-emoji v1.0.2
+	gopkg.in/yaml.v2 v2.3.0
 )
 
 require (
@@ -35,5 +37,4 @@ require (
 	golang.org/x/sys v0.17.0 // indirect
 	golang.org/x/term v0.17.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
-	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
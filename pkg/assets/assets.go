@@ -8,13 +8,17 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-//go:embed templates assets
+//go:embed templates assets starters
 var Assets embed.FS
 
-var pageTmpl *template.Template
+var (
+	pageTmplMu sync.Mutex
+	pageTmpl   *template.Template
+)
 
 type NavPage struct {
 	Title string
@@ -39,6 +43,8 @@ type PageTemplateData struct {
 	Prev        NavPage
 	Next        NavPage
 	BasePath    string
+	Mermaid     bool
+	Data        map[string]interface{}
 }
 
 func (page *PageTemplateData) FormattedUpdatedAt() string {
@@ -46,19 +52,35 @@ func (page *PageTemplateData) FormattedUpdatedAt() string {
 }
 
 func (page *PageTemplateData) Execute(file *os.File) error {
+	tmpl, err := pageTemplate()
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(file, page)
+}
+
+// pageTemplate parses the page template the first time a page is written,
+// which can be from several workers at once.
+func pageTemplate() (*template.Template, error) {
+	pageTmplMu.Lock()
+	defer pageTmplMu.Unlock()
+
+	if pageTmpl != nil {
+		return pageTmpl, nil
+	}
+
 	pageTemplateContent, err := ReadTemplate("page.html")
 	if err != nil {
-		return fmt.Errorf("Could not read template: %s", err.Error())
+		return nil, fmt.Errorf("Could not read template: %s", err.Error())
 	}
 
-	if pageTmpl == nil {
-		pageTmpl, err = template.New("page").Parse(string(pageTemplateContent))
-		if err != nil {
-			return fmt.Errorf("Could not parse template: %s", err.Error())
-		}
+	pageTmpl, err = template.New("page").Parse(string(pageTemplateContent))
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse template: %s", err.Error())
 	}
 
-	return pageTmpl.Execute(file, page)
+	return pageTmpl, nil
 }
 
 func (page *PageTemplateData) JoinPath(path string) string {
@@ -1,6 +1,7 @@
 /** @type {import('tailwindcss').Config} */
 export default {
   content: ["./css/**/*.css", "./templates/**/*"],
+  darkMode: "class",
   theme: {
     extend: {},
   },
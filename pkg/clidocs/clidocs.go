@@ -0,0 +1,293 @@
+package clidocs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	cli "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Page is the markdown page of an app or one of its commands.
+type Page struct {
+	// Name is the name of the file, with a numeric prefix that keeps the
+	// pages in the order of the commands.
+	Name     string
+	Title    string
+	Markdown string
+}
+
+type matter struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+	Section     string `yaml:"section,omitempty"`
+}
+
+type command struct {
+	path        []string
+	usage       string
+	usageText   string
+	description string
+	argsUsage   string
+	aliases     []string
+	flags       []cli.Flag
+	commands    []*cli.Command
+}
+
+// Pages creates a page for the app and for each of its commands and
+// subcommands, in the nav section. Hidden commands and flags, and the help
+// command and flag, are left out.
+func Pages(app *cli.App, section string) []Page {
+	pages := []Page{}
+
+	root := command{
+		path:        []string{app.Name},
+		usage:       app.Usage,
+		usageText:   app.UsageText,
+		description: app.Description,
+		argsUsage:   app.ArgsUsage,
+		flags:       app.VisibleFlags(),
+		commands:    visibleCommands(app.VisibleCommands()),
+	}
+
+	var walk func(cmd command, globalFlags []cli.Flag)
+	walk = func(cmd command, globalFlags []cli.Flag) {
+		pages = append(pages, page(cmd, globalFlags, section, len(pages)+1))
+
+		for _, sub := range cmd.commands {
+			walk(command{
+				path:        append(cmd.path[:len(cmd.path):len(cmd.path)], sub.Name),
+				usage:       sub.Usage,
+				usageText:   sub.UsageText,
+				description: sub.Description,
+				argsUsage:   sub.ArgsUsage,
+				aliases:     sub.Aliases,
+				flags:       sub.VisibleFlags(),
+				commands:    visibleCommands(sub.VisibleCommands()),
+			}, globalFlags)
+		}
+	}
+
+	walk(root, root.flags)
+
+	return pages
+}
+
+// Write writes the pages of the app to the directory, replacing pages that
+// were written before.
+func Write(app *cli.App, dir string, section string) ([]string, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	// pages of commands that were renamed or removed would be left behind,
+	// and pages of commands that moved would be duplicated
+	pageRegExp := regexp.MustCompile(`^\d+-` + regexp.QuoteMeta(app.Name) + `(-.+)?\.md$`)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && pageRegExp.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	paths := []string{}
+
+	for _, page := range Pages(app, section) {
+		path := filepath.Join(dir, page.Name)
+
+		if err := os.WriteFile(path, []byte(page.Markdown), 0644); err != nil {
+			return paths, fmt.Errorf("Could not write file: %s", err.Error())
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func page(cmd command, globalFlags []cli.Flag, section string, number int) Page {
+	title := strings.Join(cmd.path, " ")
+
+	var md strings.Builder
+
+	description := cmd.usage
+	if description == "" {
+		description = firstLine(cmd.description)
+	}
+
+	frontmatter, _ := yaml.Marshal(matter{
+		Title:       title,
+		Description: description,
+		Section:     section,
+	})
+
+	md.WriteString("---\n")
+	md.Write(frontmatter)
+	md.WriteString("---\n\n")
+	fmt.Fprintf(&md, "# %s\n\n", title)
+
+	if cmd.usage != "" {
+		fmt.Fprintf(&md, "%s\n\n", cmd.usage)
+	}
+
+	if cmd.description != "" && cmd.description != cmd.usage {
+		fmt.Fprintf(&md, "%s\n\n", cmd.description)
+	}
+
+	md.WriteString("## Usage\n\n```sh\n")
+	if cmd.usageText != "" {
+		md.WriteString(strings.TrimRight(cmd.usageText, "\n"))
+	} else {
+		md.WriteString(usage(cmd, len(globalFlags) > 0))
+	}
+	md.WriteString("\n```\n\n")
+
+	if len(cmd.aliases) > 0 {
+		fmt.Fprintf(&md, "Aliases: `%s`\n\n", strings.Join(cmd.aliases, "`, `"))
+	}
+
+	flags := visibleFlags(cmd.flags)
+	if len(cmd.path) == 1 {
+		flagTable(&md, "Global options", flags)
+	} else {
+		flagTable(&md, "Options", flags)
+	}
+
+	if len(cmd.commands) > 0 {
+		md.WriteString("## Commands\n\n")
+		for _, sub := range cmd.commands {
+			target := strings.Join(append(cmd.path[:len(cmd.path):len(cmd.path)], sub.Name), " ")
+			fmt.Fprintf(&md, "- [`%s`](page:%s)", sub.Name, strings.ReplaceAll(target, " ", "%20"))
+			if sub.Usage != "" {
+				fmt.Fprintf(&md, ": %s", sub.Usage)
+			} else if sub.Description != "" {
+				fmt.Fprintf(&md, ": %s", firstLine(sub.Description))
+			}
+			md.WriteString("\n")
+		}
+		md.WriteString("\n")
+	}
+
+	return Page{
+		Name:     fmt.Sprintf("%02d-%s.md", number, strings.Join(cmd.path, "-")),
+		Title:    title,
+		Markdown: md.String(),
+	}
+}
+
+func usage(cmd command, hasGlobalFlags bool) string {
+	parts := []string{cmd.path[0]}
+
+	if hasGlobalFlags {
+		parts = append(parts, "[global options]")
+	}
+
+	parts = append(parts, cmd.path[1:]...)
+
+	if len(cmd.path) > 1 && len(visibleFlags(cmd.flags)) > 0 {
+		parts = append(parts, "[options]")
+	}
+
+	if len(cmd.commands) > 0 {
+		parts = append(parts, "<command>")
+	}
+
+	if cmd.argsUsage != "" {
+		parts = append(parts, cmd.argsUsage)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func flagTable(md *strings.Builder, heading string, flags []cli.Flag) {
+	if len(flags) == 0 {
+		return
+	}
+
+	fmt.Fprintf(md, "## %s\n\n", heading)
+	md.WriteString("| Flag | Description | Default | Environment |\n")
+	md.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, flag := range flags {
+		names := []string{}
+		for _, name := range flag.Names() {
+			prefix := "--"
+			if len(name) == 1 {
+				prefix = "-"
+			}
+			names = append(names, "`"+prefix+name+"`")
+		}
+
+		description, defaultText, envVars := "", "", []string{}
+
+		if docFlag, ok := flag.(cli.DocGenerationFlag); ok {
+			description = docFlag.GetUsage()
+			if docFlag.TakesValue() {
+				defaultText = docFlag.GetDefaultText()
+			}
+			for _, env := range docFlag.GetEnvVars() {
+				envVars = append(envVars, "`"+env+"`")
+			}
+		}
+
+		if requiredFlag, ok := flag.(cli.RequiredFlag); ok && requiredFlag.IsRequired() {
+			description = strings.TrimSpace("**Required.** " + description)
+		}
+
+		if defaultText != "" {
+			defaultText = "`" + defaultText + "`"
+		}
+
+		fmt.Fprintf(md, "| %s | %s | %s | %s |\n", strings.Join(names, ", "), tableCell(description), tableCell(defaultText), strings.Join(envVars, ", "))
+	}
+
+	md.WriteString("\n")
+}
+
+func visibleFlags(flags []cli.Flag) []cli.Flag {
+	visible := []cli.Flag{}
+
+	for _, flag := range flags {
+		if flag.Names()[0] == "help" {
+			continue
+		}
+		if visibleFlag, ok := flag.(cli.VisibleFlag); ok && !visibleFlag.IsVisible() {
+			continue
+		}
+		visible = append(visible, flag)
+	}
+
+	return visible
+}
+
+func visibleCommands(commands []*cli.Command) []*cli.Command {
+	visible := []*cli.Command{}
+
+	for _, command := range commands {
+		if command.Name != "help" {
+			visible = append(visible, command)
+		}
+	}
+
+	return visible
+}
+
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", "\\|"), "\n", " ")
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+
+	return line
+}
@@ -0,0 +1,88 @@
+package cmds
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// ParseTrailingFlags sets the flags given after the arguments of a command,
+// such as the --section of gocden new page guides/deploy --section Guides,
+// which the flag parser leaves in the arguments as it stops at the first one.
+// It is a BeforeFunc, so the flags are set before the config is loaded.
+func ParseTrailingFlags(c *cli.Context) error {
+	_, err := splitArgs(c)
+	return err
+}
+
+// Args returns the arguments of a command without the flags given after them.
+func Args(c *cli.Context) []string {
+	args, _ := splitArgs(c)
+	return args
+}
+
+func splitArgs(c *cli.Context) ([]string, error) {
+	args := []string{}
+	rest := c.Args().Slice()
+
+	for len(rest) > 0 {
+		arg := rest[0]
+		rest = rest[1:]
+
+		if arg == "--" {
+			args = append(args, rest...)
+			break
+		}
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			args = append(args, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		flag := lookupFlag(c, name)
+		if flag == nil {
+			return args, fmt.Errorf("flag provided but not defined: -%s", name)
+		}
+
+		if !hasValue {
+			if valueFlag, ok := flag.(interface{ TakesValue() bool }); ok && !valueFlag.TakesValue() {
+				value = "true"
+			} else if len(rest) == 0 {
+				return args, fmt.Errorf("flag needs an argument: -%s", name)
+			} else {
+				value, rest = rest[0], rest[1:]
+			}
+		}
+
+		if err := c.Set(name, value); err != nil {
+			return args, fmt.Errorf("invalid value %q for flag -%s: %v", value, name, err)
+		}
+	}
+
+	return args, nil
+}
+
+// lookupFlag returns the flag of the command or of one of its parents with
+// the name or alias.
+func lookupFlag(c *cli.Context, name string) cli.Flag {
+	flags := []cli.Flag{}
+	for _, ctx := range c.Lineage() {
+		if ctx.Command != nil {
+			flags = append(flags, ctx.Command.Flags...)
+		}
+	}
+	flags = append(flags, c.App.Flags...)
+
+	for _, flag := range flags {
+		for _, flagName := range flag.Names() {
+			if flagName == name {
+				return flag
+			}
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,8 @@ package build
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"log/slog"
@@ -9,6 +11,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,13 +21,17 @@ import (
 	cp "github.com/otiai10/copy"
 
 	"github.com/adrg/frontmatter"
+	"github.com/alecthomas/chroma/styles"
 	"github.com/briandowns/spinner"
 	"github.com/lukeshay/gocden/pkg/assets"
 	"github.com/lukeshay/gocden/pkg/cmds"
 	"github.com/lukeshay/gocden/pkg/config"
+	"github.com/lukeshay/gocden/pkg/data"
 	"github.com/lukeshay/gocden/pkg/markdown"
+	"github.com/lukeshay/gocden/pkg/shortcodes"
 	"github.com/lukeshay/gocden/pkg/validation"
 	cli "github.com/urfave/cli/v2"
+	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/parser"
 )
 
@@ -35,17 +43,23 @@ type DocMatter struct {
 }
 
 type DocFile struct {
-	Path     string
-	OutPath  string
-	InPath   string
-	Matter   DocMatter
-	File     *os.File
-	Contents string
-	ModTime  time.Time
+	Path         string
+	OutPath      string
+	InPath       string
+	Matter       DocMatter
+	Contents     string
+	ModTime      time.Time
+	Dependencies []string
+	Warnings     []string
+	Mermaid      bool
+	// Markdown is the markdown of a page after includes and shortcodes, with
+	// the files, relative to the working directory, and lines that its lines
+	// come from. Both are empty for generated pages.
+	Markdown []byte
+	Lines    shortcodes.Lines
 }
 
 var (
-	md                  = markdown.Create()
 	mdRegExp            = regexp.MustCompile("^.+\\.(md)$")
 	fileNameOrderRegExp = regexp.MustCompile("/(\\d+)-")
 )
@@ -71,7 +85,12 @@ func Build(c *cli.Context) error {
 	spin.Suffix = " Building docs..."
 	spin.Start()
 
-	_, navSections, err := BuildAllFiles(c)
+	files, navSections, err := BuildAllFiles(c)
+	if err != nil {
+		spin.Stop()
+
+		return fmt.Errorf("Error building docs: %w", err)
+	}
 
 	sitemap := sitemapStart
 
@@ -83,20 +102,15 @@ func Build(c *cli.Context) error {
 
 	sitemap += sitemapEnd
 
-	sitemapPath := filepath.Join(conf.Build.Output, "sitemap.xml")
+	sitemapPath := filepath.Join(cmds.GetCwdFlag(c), conf.Build.Output, "sitemap.xml")
 
 	if err := os.WriteFile(sitemapPath, []byte(sitemap), 0755); err != nil {
-		fmt.Printf("Error writing sitemap: %s\n", err.Error())
-
-		return err
+		return fmt.Errorf("Error writing sitemap: %w", err)
 	}
 
 	spin.Stop()
 
-	if err != nil {
-		fmt.Printf("Error building docs: %s\n", err.Error())
-		return err
-	}
+	PrintWarnings(*files)
 
 	fmt.Println("Docs built successfully")
 
@@ -110,6 +124,11 @@ func BuildAllFiles(c *cli.Context) (*[]DocFile, *[]*assets.NavSection, error) {
 	srcDir := filepath.Join(cwd, conf.Build.Source)
 	outDir := filepath.Join(cwd, conf.Build.Output)
 
+	site, err := ParseAllFiles(c.Context, conf, cwd)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	os.RemoveAll(outDir)
 	os.MkdirAll(outDir, os.ModePerm)
 
@@ -117,7 +136,102 @@ func BuildAllFiles(c *cli.Context) (*[]DocFile, *[]*assets.NavSection, error) {
 		return nil, nil, multierror.Prefix(err, "Could not copy gocden assets")
 	}
 
+	if err := WriteChromaCSS(conf, outDir); err != nil {
+		return nil, nil, multierror.Prefix(err, "Could not write code highlighting styles")
+	}
+
+	for _, path := range site.Static {
+		if err := cp.Copy(path, strings.Replace(path, srcDir, outDir, 1)); err != nil {
+			return nil, nil, fmt.Errorf("Error copying file %s: %v", filepath.Base(path), err)
+		}
+	}
+
+	files := site.Files
+	navSections := site.NavSections
+
+	jobs := c.Int("jobs")
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+
+	slog.Info("Writing html files", "sections", navSections, "jobs", jobs)
+
+	var wg sync.WaitGroup
+	var resultMu sync.Mutex
+	var result *multierror.Error
+
+	indexes := make(chan int)
+
+	for worker := 0; worker < jobs; worker++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range indexes {
+				if err := BuildFile(files, conf, navSections, site.Data, idx, files[idx]); err != nil {
+					resultMu.Lock()
+					result = multierror.Append(result, err)
+					resultMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	// no more pages are handed to the workers once the build is canceled,
+	// the pages they are writing are finished
+	func() {
+		defer close(indexes)
+
+		for idx := range files {
+			select {
+			case indexes <- idx:
+			case <-c.Context.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	if err := c.Context.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &files, &navSections, result.ErrorOrNil()
+}
+
+// Site is the pages of a site and its nav, data, and the files of the source
+// directory that are copied as they are.
+type Site struct {
+	Files       []DocFile
+	NavSections []*assets.NavSection
+	Data        map[string]interface{}
+	Static      []string
+}
+
+// ParseAllFiles reads and converts the pages of the site and generates its
+// reference pages, without writing anything. The errors of all invalid pages
+// are returned together, with the site of the other pages, so they can be
+// reported at once. It stops with the error of ctx when ctx is canceled.
+func ParseAllFiles(ctx context.Context, conf *config.Config, cwd string) (*Site, error) {
+	srcDir := filepath.Join(cwd, conf.Build.Source)
+	outDir := filepath.Join(cwd, conf.Build.Output)
+
+	md := markdown.Create(conf)
+
+	siteData, err := data.Load(filepath.Join(srcDir, data.Dir))
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := shortcodes.Load(filepath.Join(cwd, shortcodes.Dir), siteData)
+	if err != nil {
+		return nil, err
+	}
+
 	files := []DocFile{}
+	static := []string{}
 	navSections := []*assets.NavSection{
 		{
 			Title: "",
@@ -125,87 +239,208 @@ func BuildAllFiles(c *cli.Context) (*[]DocFile, *[]*assets.NavSection, error) {
 		},
 	}
 
+	var invalid *multierror.Error
+
+	// pages and static files that are written to the same output path are
+	// found here, as they would overwrite each other when the site is written
+	outs := newOutputs(outDir)
+
+	displayPath := func(path string) string {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			return rel
+		}
+		return path
+	}
+
 	if err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
 		slog.Info("Processing file in src directory", "src", srcDir, "path", path)
 
-		if err != nil || info.IsDir() {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		if err != nil {
+			return nil
+		}
+
+		// the data directory is loaded for templates and is not copied
+		if info.IsDir() {
+			if path == filepath.Join(srcDir, data.Dir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// markdown files starting with _ or in directories starting with _
+		// are partials, not pages, while other files starting with _, such as
+		// _redirects, are copied as they are
+		if mdRegExp.MatchString(info.Name()) && isPartial(srcDir, path) {
 			return nil
 		}
 
 		if !mdRegExp.MatchString(info.Name()) {
-			if err := cp.Copy(path, strings.Replace(path, srcDir, outDir, 1)); err != nil {
-				return fmt.Errorf("Error copying file %s: %v", info.Name(), err)
+			if err := outs.add(strings.Replace(path, srcDir, outDir, 1), displayPath(path)); err != nil {
+				invalid = multierror.Append(invalid, err)
+			} else {
+				static = append(static, path)
 			}
 		}
 
-		file, err := CreateDocFile(conf, srcDir, outDir, path, info)
+		file, err := CreateDocFile(conf, md, sc, cwd, srcDir, outDir, path, info)
 		if err != nil {
-			return err
+			// the errors of all pages are reported together
+			invalid = multierror.Append(invalid, err)
+			return nil
 		} else if file == nil {
 			return nil
 		}
 
-		found := false
+		if err := outs.add(file.OutPath, displayPath(path)); err != nil {
+			invalid = multierror.Append(invalid, err)
+			return nil
+		}
 
-		for _, section := range navSections {
-			if section.Title == file.Matter.Section {
-				slog.Info("Adding page to section", "section", section.Title, "page", file.Matter.Title, "href", file.Path)
+		navSections = addNavPage(navSections, file)
 
-				section.Pages = append(section.Pages, assets.NavPage{
-					Title: file.Matter.Title,
-					Href:  file.Path,
-				})
+		files = append(files, *file)
 
-				found = true
-				break
-			}
+		return nil
+	}); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
 		}
 
-		if !found {
-			slog.Info("Creating new section for page", "section", file.Matter.Section, "page", file.Matter.Title, "href", file.Path)
-
-			navSections = append(navSections, &assets.NavSection{
-				Title: file.Matter.Section,
-				Pages: []assets.NavPage{
-					{
-						Title: file.Matter.Title,
-						Href:  file.Path,
-					},
-				},
-			})
+		return nil, multierror.Prefix(err, "Could not walk src directory")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	goDocFiles, err := CreateGoDocFiles(conf, cwd, outDir)
+	if err != nil {
+		return nil, multierror.Prefix(err, "Could not generate API reference")
+	}
+
+	openAPIFiles, err := CreateOpenAPIFiles(conf, md, cwd, outDir)
+	if err != nil {
+		return nil, multierror.Prefix(err, "Could not generate OpenAPI reference")
+	}
+
+	for _, file := range append(goDocFiles, openAPIFiles...) {
+		if err := outs.add(file.OutPath, displayPath(file.InPath)); err != nil {
+			invalid = multierror.Append(invalid, err)
+			continue
 		}
 
-		files = append(files, *file)
+		navSections = addNavPage(navSections, &file)
 
-		return nil
-	}); err != nil {
-		return nil, nil, multierror.Prefix(err, "Could not walk src directory")
+		files = append(files, file)
 	}
 
-	slog.Info("Writing html files", "sections", navSections)
+	ResolvePageLinks(conf, cwd, srcDir, files)
 
-	var wg sync.WaitGroup
-	var result *multierror.Error
+	site := &Site{
+		Files:       files,
+		NavSections: navSections,
+		Data:        siteData,
+		Static:      static,
+	}
 
-	wg.Add(len(files))
+	return site, invalid.ErrorOrNil()
+}
 
-	for idx, file := range files {
-		go func(idx int, file DocFile) {
-			defer wg.Done()
-			defer file.File.Close()
+// isPartial returns whether the file, or a directory it is in below the source
+// directory, starts with _.
+func isPartial(srcDir string, path string) bool {
+	rel, err := filepath.Rel(srcDir, path)
+	if err != nil {
+		return false
+	}
 
-			if err := BuildFile(files, conf, navSections, idx, file); err != nil {
-				result = multierror.Append(result, err)
-			}
-		}(idx, file)
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(part, "_") {
+			return true
+		}
 	}
 
-	wg.Wait()
+	return false
+}
 
-	return &files, &navSections, result.ErrorOrNil()
+// OrderPrefix returns the number of the ordering prefix of a file or
+// directory name, such as 2 for 02-guides, and the name without it.
+func OrderPrefix(name string) (int, string, bool) {
+	m := fileNameOrderRegExp.FindStringSubmatchIndex("/" + name)
+	if m == nil || m[0] != 0 {
+		return 0, name, false
+	}
+
+	number, err := strconv.Atoi(name[m[2]-1 : m[3]-1])
+	if err != nil {
+		return 0, name, false
+	}
+
+	return number, name[m[1]-1:], true
+}
+
+// addNavPage adds the page to the nav section named in its frontmatter,
+// creating the section if it is the first page in it.
+func addNavPage(navSections []*assets.NavSection, file *DocFile) []*assets.NavSection {
+	for _, section := range navSections {
+		if section.Title == file.Matter.Section {
+			slog.Info("Adding page to section", "section", section.Title, "page", file.Matter.Title, "href", file.Path)
+
+			section.Pages = append(section.Pages, assets.NavPage{
+				Title: file.Matter.Title,
+				Href:  file.Path,
+			})
+
+			return navSections
+		}
+	}
+
+	slog.Info("Creating new section for page", "section", file.Matter.Section, "page", file.Matter.Title, "href", file.Path)
+
+	return append(navSections, &assets.NavSection{
+		Title: file.Matter.Section,
+		Pages: []assets.NavPage{
+			{
+				Title: file.Matter.Title,
+				Href:  file.Path,
+			},
+		},
+	})
 }
 
-func BuildFile(files []DocFile, conf *config.Config, navSections []*assets.NavSection, idx int, file DocFile) error {
+func PrintWarnings(files []DocFile) {
+	for _, file := range files {
+		for _, warning := range file.Warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+	}
+}
+
+func WriteChromaCSS(conf *config.Config, outDir string) error {
+	light, ok := styles.Registry[conf.Highlight.Style]
+	if !ok {
+		return fmt.Errorf("Unknown highlight style %q, expected one of: %s", conf.Highlight.Style, strings.Join(styles.Names(), ", "))
+	}
+
+	dark, ok := styles.Registry[conf.Highlight.DarkStyle]
+	if !ok {
+		return fmt.Errorf("Unknown highlight dark style %q, expected one of: %s", conf.Highlight.DarkStyle, strings.Join(styles.Names(), ", "))
+	}
+
+	cssFile, err := os.Create(filepath.Join(outDir, "chroma.css"))
+	if err != nil {
+		return err
+	}
+	defer cssFile.Close()
+
+	return markdown.WriteCSS(cssFile, light, dark)
+}
+
+func BuildFile(files []DocFile, conf *config.Config, navSections []*assets.NavSection, siteData map[string]interface{}, idx int, file DocFile) error {
 	if info, err := os.Stat(filepath.Dir(file.OutPath)); err != nil || !info.IsDir() {
 		_ = os.MkdirAll(filepath.Dir(file.OutPath), os.ModePerm)
 	}
@@ -263,6 +498,8 @@ func BuildFile(files []DocFile, conf *config.Config, navSections []*assets.NavSe
 			Href:  next.Path,
 		},
 		BasePath: basePath,
+		Mermaid:  file.Mermaid,
+		Data:     siteData,
 	}
 
 	if err := page.Execute(dstHtmlFile); err != nil {
@@ -272,7 +509,7 @@ func BuildFile(files []DocFile, conf *config.Config, navSections []*assets.NavSe
 	return nil
 }
 
-func CreateDocFile(conf *config.Config, srcDir string, outDir string, path string, info os.FileInfo) (*DocFile, error) {
+func CreateDocFile(conf *config.Config, md goldmark.Markdown, sc *shortcodes.Shortcodes, cwd string, srcDir string, outDir string, path string, info os.FileInfo) (*DocFile, error) {
 	slog.Info("Processing file in src directory", "src", srcDir, "path", path)
 
 	if info.IsDir() || !mdRegExp.MatchString(info.Name()) {
@@ -281,29 +518,108 @@ func CreateDocFile(conf *config.Config, srcDir string, outDir string, path strin
 
 	slog.Info("Adding file to list", "path", path)
 
-	markdownFile, err := os.Open(path)
+	var matter DocMatter
+
+	displayPath, err := filepath.Rel(cwd, path)
 	if err != nil {
-		return nil, fmt.Errorf("Could not open file: %v", err)
+		displayPath = path
 	}
 
-	var matter DocMatter
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &Error{File: displayPath, Err: fmt.Errorf("Could not read file: %v", err)}
+	}
 
-	pageMarkdown, err := frontmatter.MustParse(markdownFile, &matter)
+	pageMarkdown, err := frontmatter.MustParse(bytes.NewReader(content), &matter)
 	if err != nil {
-		return nil, fmt.Errorf("Could not parse frontmatter: %v", err)
+		return nil, &Error{File: displayPath, Err: fmt.Errorf("Could not parse frontmatter: %v", err)}
 	}
 
-	if err := validation.ValidateAndPrint(fmt.Sprintf("The frontmatter is invalid in %s", info.Name()), &matter); err != nil {
-		return nil, err
+	if errs := validation.Validate(&matter, "yaml", "frontmatter field"); len(errs) > 0 {
+		format, block, start := frontmatterBlock(content, pageMarkdown)
+
+		var result *multierror.Error
+		for _, err := range errs {
+			err.File = displayPath
+			err.Line = validation.Line(block, format, start, err.Key)
+			result = multierror.Append(result, err)
+		}
+
+		return nil, result
+	}
+
+	lineOffset := 0
+	if bytes.HasSuffix(content, pageMarkdown) {
+		lineOffset = bytes.Count(content[:len(content)-len(pageMarkdown)], []byte("\n"))
+	}
+
+	// locate returns the file and line of the page or an included file that
+	// a location of the included markdown is
+	locate := func(loc shortcodes.Location) shortcodes.Location {
+		if loc.File == "" {
+			return shortcodes.Location{File: displayPath, Line: loc.Line + lineOffset}
+		}
+		if rel, err := filepath.Rel(cwd, loc.File); err == nil {
+			loc.File = rel
+		}
+		return loc
+	}
+
+	inclusion, err := shortcodes.Include(pageMarkdown, path, srcDir, cwd)
+	if err != nil {
+		return nil, contentError(err, displayPath, locate)
+	}
+
+	expansion, err := sc.Expand(inclusion.Markdown)
+	if err != nil {
+		return nil, contentError(err, displayPath, func(loc shortcodes.Location) shortcodes.Location {
+			return locate(inclusion.Lines.At(loc.Line))
+		})
+	}
+
+	lines := make(shortcodes.Lines, 0, len(expansion.Lines))
+	for _, loc := range expansion.Lines {
+		lines = append(lines, locate(inclusion.Lines.At(loc.Line)))
 	}
 
 	var markdownHtmlBuf bytes.Buffer
 
-	if err := md.Convert(pageMarkdown, &markdownHtmlBuf, parser.WithContext(parser.NewContext())); err != nil {
-		return nil, fmt.Errorf("Could not convert markdown to html: %v", err)
+	pc := markdown.NewContext(cwd)
+	markdown.SetLinks(pc, conf.Links)
+
+	if err := md.Convert(expansion.Markdown, &markdownHtmlBuf, parser.WithContext(pc)); err != nil {
+		return nil, &Error{File: displayPath, Err: fmt.Errorf("Could not convert markdown to html: %v", err)}
+	}
+
+	if err := markdown.Err(pc); err != nil {
+		errs := []error{err}
+		var convertErr *multierror.Error
+		if errors.As(err, &convertErr) {
+			errs = convertErr.Errors
+		}
+
+		var result *multierror.Error
+		for _, err := range errs {
+			result = multierror.Append(result, &Error{File: displayPath, Err: err})
+		}
+
+		return nil, result
 	}
 
-	markdownHtml := markdownHtmlBuf.String()
+	markdownHtml := expansion.Restore(markdownHtmlBuf.String())
+
+	warnings := []string{}
+	for _, warning := range markdown.Warnings(pc) {
+		if warning.Line > 0 {
+			loc := lines.At(warning.Line)
+			if loc.File == "" {
+				loc = locate(loc)
+			}
+			warnings = append(warnings, fmt.Sprintf("%s:%d: %s", loc.File, loc.Line, warning.Message))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", displayPath, warning.Message))
+		}
+	}
 
 	var dstPath string
 
@@ -321,12 +637,74 @@ func CreateDocFile(conf *config.Config, srcDir string, outDir string, path strin
 
 	file := &DocFile{
 		Path: finalPath, OutPath: dstPath,
-		InPath:   path,
-		Matter:   matter,
-		File:     markdownFile,
-		Contents: markdownHtml,
-		ModTime:  info.ModTime(),
+		InPath:       path,
+		Matter:       matter,
+		Contents:     markdownHtml,
+		ModTime:      info.ModTime(),
+		Dependencies: append(append(markdown.Dependencies(pc), inclusion.Dependencies...), expansion.Dependencies...),
+		Warnings:     warnings,
+		Mermaid:      markdown.UsesMermaid(pc),
+		Markdown:     expansion.Markdown,
+		Lines:        lines,
 	}
 
 	return file, nil
 }
+
+// frontmatterBlock returns the format of the frontmatter of the page, the
+// frontmatter and the line of the page it starts at.
+func frontmatterBlock(content []byte, pageMarkdown []byte) (string, []byte, int) {
+	block := content
+	if bytes.HasSuffix(content, pageMarkdown) {
+		block = content[:len(content)-len(pageMarkdown)]
+	}
+
+	// frontmatter in braces is json, and the braces are part of it
+	if bytes.HasPrefix(bytes.TrimSpace(block), []byte("{")) {
+		return "json", block, 1
+	}
+
+	delimiter, rest, _ := bytes.Cut(block, []byte("\n"))
+
+	switch string(bytes.TrimSpace(delimiter)) {
+	case "+++", "---toml":
+		return "toml", rest, 2
+	case ";;;", "---json":
+		return "json", rest, 2
+	}
+
+	return "yaml", rest, 2
+}
+
+// Error is an error in a page, or in a file that it includes. Line is 0 when
+// the error is not on a line.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", e.File, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// contentError returns an error in an include or shortcode with the file and
+// line it is on, which locate returns for the location of the error.
+func contentError(err error, displayPath string, locate func(shortcodes.Location) shortcodes.Location) error {
+	var shortcodeErr *shortcodes.Error
+	if !errors.As(err, &shortcodeErr) {
+		return &Error{File: displayPath, Err: err}
+	}
+
+	loc := locate(shortcodes.Location{File: shortcodeErr.File, Line: shortcodeErr.Line})
+
+	return &Error{File: loc.File, Line: loc.Line, Err: shortcodeErr.Err}
+}
@@ -0,0 +1,58 @@
+package build
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/config"
+	"github.com/lukeshay/gocden/pkg/godoc"
+)
+
+// CreateGoDocFiles creates the API reference pages of the Go packages in the
+// godoc section of the config. The pages are generated, so they have no
+// source file, but they depend on the Go files of their package.
+func CreateGoDocFiles(conf *config.Config, cwd string, outDir string) ([]DocFile, error) {
+	if conf.GoDoc == nil || len(conf.GoDoc.Packages) == 0 {
+		return []DocFile{}, nil
+	}
+
+	packages, err := godoc.Load(cwd, conf.GoDoc.Packages)
+	if err != nil {
+		return nil, err
+	}
+
+	pagePaths := map[string]string{}
+	for _, pkg := range packages {
+		pagePaths[pkg.ImportPath] = "/" + path.Join(strings.Trim(conf.GoDoc.Path, "/"), pkg.RelPath+".html")
+	}
+
+	linker := func(importPath string) (string, bool) {
+		pagePath, ok := pagePaths[importPath]
+
+		return "page:" + strings.TrimPrefix(pagePath, "/"), ok
+	}
+
+	files := make([]DocFile, 0, len(packages))
+
+	for _, pkg := range packages {
+		pagePath := pagePaths[pkg.ImportPath]
+
+		files = append(files, DocFile{
+			Path:    pagePath,
+			OutPath: filepath.Join(outDir, filepath.FromSlash(pagePath)),
+			InPath:  pkg.Dir,
+			Matter: DocMatter{
+				Title:       pkg.RelPath,
+				Description: pkg.Doc.Synopsis(pkg.Doc.Doc),
+				Section:     conf.GoDoc.Section,
+			},
+			Contents:     pkg.HTML(linker),
+			ModTime:      pkg.ModTime,
+			Dependencies: pkg.Files,
+			Warnings:     []string{},
+		})
+	}
+
+	return files, nil
+}
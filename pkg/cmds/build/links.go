@@ -0,0 +1,95 @@
+package build
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/config"
+)
+
+var (
+	pageLinkRegExp      = regexp.MustCompile(`href="page:([^"#]*)(#[^"]*)?"`)
+	pageOrderPartRegExp = regexp.MustCompile(`(^|/)\d+-`)
+)
+
+// ResolvePageLinks replaces links with a page: target, such as
+// page:basics/configuration or page:Getting Started, with the path of the
+// page. A page can be referenced by its source path, its output path or its
+// title. Links that cannot be resolved are left as is and reported as
+// warnings on the page.
+func ResolvePageLinks(conf *config.Config, cwd string, srcDir string, files []DocFile) {
+	basePath := ""
+	if uri, err := url.Parse(conf.Url); err == nil {
+		basePath = uri.Path
+	}
+
+	pages := map[string]*DocFile{}
+
+	for idx := range files {
+		file := &files[idx]
+
+		for _, key := range pageLinkKeys(srcDir, file) {
+			if _, ok := pages[key]; !ok {
+				pages[key] = file
+			}
+		}
+	}
+
+	for idx := range files {
+		file := &files[idx]
+
+		file.Contents = pageLinkRegExp.ReplaceAllStringFunc(file.Contents, func(match string) string {
+			groups := pageLinkRegExp.FindStringSubmatch(match)
+
+			target, err := url.PathUnescape(groups[1])
+			if err != nil {
+				target = groups[1]
+			}
+
+			page, ok := pages[normalizePageLinkKey(target)]
+			if !ok {
+				displayPath, err := filepath.Rel(cwd, file.InPath)
+				if err != nil {
+					displayPath = file.InPath
+				}
+
+				file.Warnings = append(file.Warnings, fmt.Sprintf("%s: unresolved page link %q", displayPath, target))
+
+				return match
+			}
+
+			href, err := url.JoinPath(basePath, page.Path)
+			if err != nil {
+				return match
+			}
+
+			return fmt.Sprintf(`href="%s%s"`, href, groups[2])
+		})
+	}
+}
+
+func pageLinkKeys(srcDir string, file *DocFile) []string {
+	keys := []string{
+		normalizePageLinkKey(file.Path),
+		normalizePageLinkKey(file.Matter.Title),
+	}
+
+	if rel, err := filepath.Rel(srcDir, file.InPath); err == nil {
+		rel = filepath.ToSlash(rel)
+
+		keys = append(keys, normalizePageLinkKey(rel), normalizePageLinkKey(pageOrderPartRegExp.ReplaceAllString(rel, "$1")))
+	}
+
+	return keys
+}
+
+func normalizePageLinkKey(key string) string {
+	key = strings.ToLower(strings.Trim(strings.TrimSpace(key), "/"))
+	key = strings.TrimSuffix(key, ".md")
+	key = strings.TrimSuffix(key, ".html")
+
+	return key
+}
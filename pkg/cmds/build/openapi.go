@@ -0,0 +1,122 @@
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lukeshay/gocden/pkg/config"
+	"github.com/lukeshay/gocden/pkg/markdown"
+	"github.com/lukeshay/gocden/pkg/openapi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+)
+
+// CreateOpenAPIFiles creates the reference pages of the OpenAPI
+// specifications in the config. The pages of a specification are in their
+// own nav section, which is named after the specification unless the config
+// names it.
+func CreateOpenAPIFiles(conf *config.Config, md goldmark.Markdown, cwd string, outDir string) ([]DocFile, error) {
+	files := []DocFile{}
+
+	for _, api := range conf.OpenAPI {
+		specPath := filepath.Join(cwd, api.Spec)
+
+		spec, err := openapi.Load(specPath)
+		if err != nil {
+			return nil, err
+		}
+
+		displayPath, err := filepath.Rel(cwd, specPath)
+		if err != nil {
+			displayPath = specPath
+		}
+
+		section := api.Section
+		if section == "" {
+			section = spec.Info.Title
+		}
+
+		pagesPath := strings.Trim(api.Path, "/")
+		if pagesPath == "" {
+			pagesPath = strings.TrimSuffix(filepath.Base(api.Spec), filepath.Ext(api.Spec))
+		}
+
+		warnings := []string{}
+		dependencies := []string{specPath}
+
+		pages, specWarnings, err := spec.Pages(openapi.Options{
+			GroupBy: api.GroupBy,
+			Markdown: func(source string) string {
+				var buf bytes.Buffer
+
+				pc := markdown.NewContext(cwd)
+				markdown.SetLinks(pc, conf.Links)
+
+				if err := md.Convert([]byte(source), &buf, parser.WithContext(pc)); err != nil {
+					warnings = append(warnings, fmt.Sprintf("%s: could not convert description: %v", displayPath, err))
+				}
+
+				for _, warning := range markdown.Warnings(pc) {
+					warnings = append(warnings, fmt.Sprintf("%s: %s", displayPath, warning.Message))
+				}
+				dependencies = append(dependencies, markdown.Dependencies(pc)...)
+
+				return buf.String()
+			},
+			Link: func(slug string) string {
+				return "page:" + path.Join(pagesPath, slug)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", displayPath, err)
+		}
+
+		for _, warning := range specWarnings {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", displayPath, warning))
+		}
+
+		modTime := fileModTime(specPath)
+
+		for idx, page := range pages {
+			pagePath := "/" + path.Join(pagesPath, page.Slug+".html")
+
+			file := DocFile{
+				Path:    pagePath,
+				OutPath: filepath.Join(outDir, filepath.FromSlash(pagePath)),
+				InPath:  specPath,
+				Matter: DocMatter{
+					Title:       page.Title,
+					Description: page.Description,
+					Section:     section,
+				},
+				Contents:     page.HTML,
+				ModTime:      modTime,
+				Dependencies: dependencies,
+				Warnings:     []string{},
+			}
+
+			// the warnings are about the whole specification, so they are
+			// reported once
+			if idx == 0 {
+				file.Warnings = warnings
+			}
+
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+func fileModTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+
+	return time.Now()
+}
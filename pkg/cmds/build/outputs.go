@@ -0,0 +1,67 @@
+package build
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/assets"
+)
+
+// CollisionError is returned for a file that would be written to the same
+// path of the output directory as another file, which it would overwrite.
+type CollisionError struct {
+	// OutPath is the path in the output directory, such as guides/intro.html.
+	OutPath string
+	File    string
+	Other   string
+}
+
+func (e *CollisionError) Error() string {
+	return fmt.Sprintf("%s: output path %s is also written by %s", e.File, e.OutPath, e.Other)
+}
+
+// outputs are the files that are written to the output directory, by their
+// path in it, with the files they come from.
+type outputs struct {
+	outDir string
+	paths  map[string]string
+}
+
+// newOutputs returns the outputs with the files that gocden writes to every
+// site, its assets, highlighting styles and sitemap.
+func newOutputs(outDir string) *outputs {
+	o := &outputs{outDir: outDir, paths: map[string]string{}}
+
+	_ = fs.WalkDir(assets.Assets, "assets", func(name string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			o.paths[strings.TrimPrefix(name, "assets/")] = "gocden"
+		}
+		return err
+	})
+
+	o.paths["chroma.css"] = "gocden"
+	o.paths["sitemap.xml"] = "gocden"
+
+	return o
+}
+
+// add adds the output path of a file, or returns a CollisionError if another
+// file is written to it.
+func (o *outputs) add(outPath string, file string) error {
+	rel, err := filepath.Rel(o.outDir, outPath)
+	if err != nil {
+		rel = outPath
+	}
+	rel = path.Clean(filepath.ToSlash(rel))
+
+	if other, ok := o.paths[rel]; ok {
+		return &CollisionError{OutPath: rel, File: file, Other: other}
+	}
+
+	o.paths[rel] = file
+
+	return nil
+}
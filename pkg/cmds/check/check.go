@@ -0,0 +1,372 @@
+package check
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/lukeshay/gocden/pkg/cmds"
+	"github.com/lukeshay/gocden/pkg/cmds/build"
+	"github.com/lukeshay/gocden/pkg/config"
+	"github.com/lukeshay/gocden/pkg/validation"
+	cli "github.com/urfave/cli/v2"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Severity is how serious a finding is. Rules that are off are not run.
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+	Off     Severity = "off"
+)
+
+var severityRanks = map[Severity]int{Off: 0, Info: 1, Warning: 2, Error: 3}
+
+// Rule is a check of the pages of a site, with its default severity.
+type Rule struct {
+	Name        string
+	Description string
+	Severity    Severity
+}
+
+// Rules are the rules of gocden check.
+var Rules = []Rule{
+	{"invalid-page", "Pages that cannot be read or converted", Error},
+	{"missing-title", "Pages without a title", Error},
+	{"duplicate-path", "Pages that are written to the same output path", Error},
+	{"duplicate-title", "Pages with the same title in a nav section", Warning},
+	{"orphan-page", "Pages that no other page links to", Info},
+	{"empty-section", "Headings without content before the next heading of the same or a higher level", Warning},
+	{"image-alt", "Images without alt text", Warning},
+	{"heading-level", "Headings that skip a level, such as an h4 after an h2", Warning},
+}
+
+// Finding is a problem with a page that a rule found. Line is 0 when the
+// problem is not on a line.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Severities returns the severities of the rules, which are their defaults
+// unless the config sets them.
+func Severities(conf *config.Check) (map[string]Severity, error) {
+	severities := map[string]Severity{}
+	for _, rule := range Rules {
+		severities[rule.Name] = rule.Severity
+	}
+
+	if conf == nil {
+		return severities, nil
+	}
+
+	for name, severity := range conf.Rules {
+		if _, ok := severities[name]; !ok {
+			names := []string{}
+			for _, rule := range Rules {
+				names = append(names, rule.Name)
+			}
+
+			return nil, fmt.Errorf("Unknown rule %q in check.rules, expected one of: %s", name, strings.Join(names, ", "))
+		}
+
+		severities[name] = Severity(severity)
+	}
+
+	return severities, nil
+}
+
+// Fails returns whether a finding is at least as severe as failOn, which is
+// a severity or never.
+func Fails(findings []Finding, failOn string) bool {
+	if failOn == "never" {
+		return false
+	}
+
+	for _, finding := range findings {
+		if severityRanks[finding.Severity] >= severityRanks[Severity(failOn)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+var hrefRegExp = regexp.MustCompile(`href="([^"]*)"`)
+
+// Run runs the rules on the pages of the site. The errors of the pages that
+// could not be parsed, which ParseAllFiles returns with the site, are
+// findings too, such as the pages with duplicate output paths. Hrefs in
+// pages start with basePath, the path of the url of the site.
+func Run(site *build.Site, parseErr error, cwd string, basePath string, severities map[string]Severity) []Finding {
+	findings := []Finding{}
+
+	add := func(rule string, file string, line int, format string, args ...interface{}) {
+		severity := severities[rule]
+		if severity == Off || severity == "" {
+			return
+		}
+
+		findings = append(findings, Finding{
+			Rule:     rule,
+			Severity: severity,
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, err := range parseErrors(parseErr) {
+		var collisionErr *build.CollisionError
+		if errors.As(err, &collisionErr) {
+			add("duplicate-path", filepath.ToSlash(collisionErr.File), 0, "output path %s is also written by %s", collisionErr.OutPath, filepath.ToSlash(collisionErr.Other))
+			continue
+		}
+
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			rule := "invalid-page"
+			if validationErr.Key == "title" {
+				rule = "missing-title"
+			}
+
+			add(rule, validationErr.File, validationErr.Line, "%s", validationErr.Message)
+			continue
+		}
+
+		var pageErr *build.Error
+		if errors.As(err, &pageErr) {
+			add("invalid-page", filepath.ToSlash(pageErr.File), pageErr.Line, "%s", pageErr.Err)
+			continue
+		}
+
+		add("invalid-page", "", 0, "%s", err)
+	}
+
+	displayPath := func(file build.DocFile) string {
+		if rel, err := filepath.Rel(cwd, file.InPath); err == nil {
+			return filepath.ToSlash(rel)
+		}
+		return file.InPath
+	}
+
+	titles := map[string]build.DocFile{}
+	linked := map[string]bool{}
+
+	for _, file := range site.Files {
+		if strings.TrimSpace(file.Matter.Title) == "" {
+			add("missing-title", displayPath(file), 0, "frontmatter field %q is empty", "title")
+		}
+
+		titleKey := file.Matter.Section + "\x00" + file.Matter.Title
+		if other, ok := titles[titleKey]; ok {
+			add("duplicate-title", displayPath(file), 0, "title %q is also the title of %s in the same section", file.Matter.Title, displayPath(other))
+		} else {
+			titles[titleKey] = file
+		}
+
+		for _, match := range hrefRegExp.FindAllStringSubmatch(file.Contents, -1) {
+			if target, ok := linkTarget(html.UnescapeString(match[1]), file.Path, basePath); ok && target != file.Path {
+				linked[target] = true
+			}
+		}
+	}
+
+	for _, file := range site.Files {
+		// generated reference pages are linked from the nav only
+		generated := filepath.Ext(file.InPath) != ".md"
+
+		if file.Path != "/index.html" && !generated && !linked[file.Path] {
+			add("orphan-page", displayPath(file), 0, "no other page links to %s", file.Path)
+		}
+
+		if len(file.Markdown) > 0 {
+			checkMarkdown(file.Markdown, func(rule string, line int, format string, args ...interface{}) {
+				// lines of the markdown are in the page or in the files it includes
+				if loc := file.Lines.At(line); line > 0 && loc.File != "" {
+					add(rule, filepath.ToSlash(loc.File), loc.Line, format, args...)
+					return
+				}
+				add(rule, displayPath(file), line, format, args...)
+			})
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+}
+
+func parseErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	var result *multierror.Error
+	if errors.As(err, &result) {
+		return result.Errors
+	}
+
+	return []error{err}
+}
+
+// linkTarget returns the path of the page that an href links to, in the form
+// of the paths of pages, such as /guides/index.html. Links to other sites are
+// not pages.
+func linkTarget(href string, from string, basePath string) (string, bool) {
+	uri, err := url.Parse(href)
+	if err != nil || uri.Scheme != "" || uri.Host != "" || uri.Path == "" {
+		return "", false
+	}
+
+	target := uri.Path
+	if strings.HasPrefix(target, "/") {
+		if base := strings.TrimSuffix(basePath, "/"); base != "" {
+			if !strings.HasPrefix(target, base+"/") && target != base {
+				return "", false
+			}
+			target = strings.TrimPrefix(target, base)
+		}
+	} else {
+		target = path.Join(path.Dir(from), target)
+		if strings.HasSuffix(uri.Path, "/") {
+			target += "/"
+		}
+	}
+
+	if target == "" || strings.HasSuffix(target, "/") {
+		target += "index.html"
+	}
+
+	return path.Clean("/" + target), true
+}
+
+// checkMarkdown runs the rules on the content of a page. Lines are relative
+// to the start of the markdown.
+func checkMarkdown(source []byte, add func(rule string, line int, format string, args ...interface{})) {
+	doc := goldmark.New().Parser().Parse(text.NewReader(source))
+
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		heading, ok := child.(*ast.Heading)
+		if !ok {
+			continue
+		}
+
+		next, isHeading := child.NextSibling().(*ast.Heading)
+		if child.NextSibling() == nil || isHeading && next.Level <= heading.Level {
+			add("empty-section", blockLine(source, heading), "section %q has no content", string(heading.Text(source)))
+		}
+	}
+
+	level := 0
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := n.(type) {
+		case *ast.Heading:
+			if level > 0 && n.Level > level+1 {
+				add("heading-level", blockLine(source, n), "heading level %d skips level %d", n.Level, level+1)
+			}
+			level = n.Level
+		case *ast.Image:
+			if strings.TrimSpace(string(n.Text(source))) == "" {
+				add("image-alt", blockLine(source, n), "image %s has no alt text", string(n.Destination))
+			}
+		}
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// blockLine returns the line of the node, or of the block it is in for
+// inline nodes.
+func blockLine(source []byte, n ast.Node) int {
+	for ; n != nil; n = n.Parent() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			offset := n.Lines().At(0).Start
+			return strings.Count(string(source[:offset]), "\n") + 1
+		}
+	}
+
+	return 0
+}
+
+// Check checks the pages of the site without writing it, and fails if a
+// finding is at least as severe as --fail-on or check.fail_on.
+func Check(c *cli.Context) error {
+	cwd := cmds.GetCwdFlag(c)
+	conf := cmds.GetConfigFromCliContext(c)
+
+	severities, err := Severities(conf.Check)
+	if err != nil {
+		return err
+	}
+
+	failOn := conf.Check.FailOn
+	if c.IsSet("fail-on") {
+		failOn = c.String("fail-on")
+	}
+	if _, ok := severityRanks[Severity(failOn)]; !ok && failOn != "never" || failOn == string(Off) {
+		return fmt.Errorf("Unknown severity %q, expected one of: error, warning, info, never", failOn)
+	}
+
+	var write func(io.Writer, []Finding) error
+
+	switch c.String("format") {
+	case "text":
+		write = WriteText
+	case "json":
+		write = WriteJSON
+	case "sarif":
+		write = WriteSARIF
+	default:
+		return fmt.Errorf("Unknown format %q, expected one of: text, json, sarif", c.String("format"))
+	}
+
+	site, parseErr := build.ParseAllFiles(c.Context, conf, cwd)
+	if site == nil {
+		return parseErr
+	}
+
+	basePath := ""
+	if uri, err := url.Parse(conf.Url); err == nil {
+		basePath = uri.Path
+	}
+
+	findings := Run(site, parseErr, cwd, basePath, severities)
+
+	if err := write(os.Stdout, findings); err != nil {
+		return err
+	}
+
+	if Fails(findings, failOn) {
+		return cli.Exit("", 1)
+	}
+
+	return nil
+}
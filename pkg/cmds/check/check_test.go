@@ -0,0 +1,63 @@
+package check
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukeshay/gocden/pkg/cmds/build"
+	"github.com/lukeshay/gocden/pkg/config"
+)
+
+func TestRunLocatesPageErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"docs/index.md":   "---\ntitle: Home\n---\n\n# Home\n\n[A](a.html) [B](b.html)\n",
+		"docs/a.md":       "---\ntitle: A\n---\n\n# A\n\nText.\n\n{{< nope >}}\n",
+		"docs/b.md":       "---\ntitle: [\n---\n",
+		"docs/_part.md":   "Text.\n\n{{< nope >}}\n",
+		"docs/d.md":       "---\ntitle: D\n---\n\n# D\n\n{{< include \"_part.md\" >}}\n",
+		"docs/e:colon.md": "---\ntitle: [\n---\n",
+		"docs/f space.md": "---\ntitle: F\n---\n\n# F\n\n{{< nope >}}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := config.Default()
+	conf.Name = "Docs"
+	conf.Build.Source = "docs"
+	conf.Build.Output = "dist"
+
+	site, parseErr := build.ParseAllFiles(context.Background(), conf, dir)
+	if site == nil {
+		t.Fatalf("parse: %v", parseErr)
+	}
+
+	severities, err := Severities(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]bool{}
+	for _, finding := range Run(site, parseErr, dir, "", severities) {
+		if finding.Rule == "invalid-page" {
+			found[fmt.Sprintf("%s:%d", finding.File, finding.Line)] = true
+		}
+	}
+
+	for _, want := range []string{"docs/a.md:9", "docs/b.md:0", "docs/_part.md:3", "docs/e:colon.md:0", "docs/f space.md:7"} {
+		if !found[want] {
+			t.Errorf("no invalid-page finding at %s, found %v", want, found)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// WriteText writes the findings as lines such as
+// docs/01-index.md:3: warning: image a.png has no alt text (image-alt),
+// followed by a summary.
+func WriteText(w io.Writer, findings []Finding) error {
+	counts := map[Severity]int{}
+
+	for _, finding := range findings {
+		counts[finding.Severity]++
+
+		location := finding.File
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, finding.Line)
+		}
+		if location != "" {
+			location += ": "
+		}
+
+		if _, err := fmt.Fprintf(w, "%s%s: %s (%s)\n", location, finding.Severity, finding.Message, finding.Rule); err != nil {
+			return err
+		}
+	}
+
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No problems found")
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d errors, %d warnings, %d info\n", counts[Error], counts[Warning], counts[Info])
+
+	return err
+}
+
+// WriteJSON writes the findings as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(findings)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes the findings as a SARIF 2.1.0 log, which code scanning
+// tools such as GitHub code scanning can show on pull requests.
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	driver := sarifDriver{
+		Name:           "gocden",
+		InformationURI: "https://github.com/lukeshay/gocden",
+		Rules:          []sarifRule{},
+	}
+
+	for _, rule := range Rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.Name,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		})
+	}
+
+	results := []sarifResult{}
+
+	for _, finding := range findings {
+		result := sarifResult{
+			RuleID:  finding.Rule,
+			Level:   sarifLevel(finding.Severity),
+			Message: sarifMessage{Text: finding.Message},
+		}
+
+		if finding.File != "" {
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: finding.File},
+				},
+			}
+			if finding.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line}
+			}
+
+			result.Locations = []sarifLocation{location}
+		}
+
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{
+			{
+				Tool:    sarifTool{Driver: driver},
+				Results: results,
+			},
+		},
+	})
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Off:
+		return "none"
+	}
+
+	return "note"
+}
@@ -1,6 +1,9 @@
 package cmds
 
 import (
+	"context"
+	"path/filepath"
+
 	"github.com/lukeshay/gocden/pkg/config"
 	cli "github.com/urfave/cli/v2"
 )
@@ -9,6 +12,51 @@ func GetConfigFromCliContext(c *cli.Context) *config.Config {
 	return c.Context.Value(config.ConfigPath).(*config.Config)
 }
 
+// GetSourcesFromCliContext returns where the values of the config come from.
+func GetSourcesFromCliContext(c *cli.Context) config.Sources {
+	return c.Context.Value(sourcesContextKey{}).(config.Sources)
+}
+
 func GetCwdFlag(c *cli.Context) string {
 	return c.String("cwd")
 }
+
+type sourcesContextKey struct{}
+
+// LoadConfig reads the config file given by --config, or the one found from
+// --cwd, with the overrides of --env, for commands that need it. Unless --cwd
+// is set, the directory of the config file is the directory of the site, so
+// --cwd is set to it.
+func LoadConfig(c *cli.Context) error {
+	cwd, err := filepath.Abs(GetCwdFlag(c))
+	if err != nil {
+		return err
+	}
+
+	path := c.String("config")
+	if path == "" {
+		if path, err = config.Find(cwd); err != nil {
+			return err
+		}
+	} else if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+
+	conf, sources, err := config.Read(path, c.String("env"))
+	if err != nil {
+		return err
+	}
+
+	if !c.IsSet("cwd") {
+		cwd = filepath.Dir(path)
+	}
+
+	if err := c.Set("cwd", cwd); err != nil {
+		return err
+	}
+
+	c.Context = context.WithValue(c.Context, config.ConfigPath, conf)
+	c.Context = context.WithValue(c.Context, sourcesContextKey{}, sources)
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package configcmd
+
+import (
+	"fmt"
+
+	"github.com/lukeshay/gocden/pkg/cmds"
+	"github.com/lukeshay/gocden/pkg/config"
+	cli "github.com/urfave/cli/v2"
+)
+
+// Print prints the config with the overrides of --env and the environment
+// variables applied, and where each value comes from.
+func Print(c *cli.Context) error {
+	fmt.Print(config.Format(cmds.GetConfigFromCliContext(c), cmds.GetSourcesFromCliContext(c)))
+
+	return nil
+}
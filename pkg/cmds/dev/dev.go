@@ -4,9 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"syscall"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -21,35 +21,62 @@ func Dev(c *cli.Context) error {
 	cwd := cmds.GetCwdFlag(c)
 	config := cmds.GetConfigFromCliContext(c)
 
-	if _, _, err := build.BuildAllFiles(c); err != nil {
+	files, _, err := build.BuildAllFiles(c)
+	if err != nil {
 		return err
 	}
 
+	build.PrintWarnings(*files)
+
+	// the server stops when gocden is interrupted, which ends the command
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := serve.RunServer(c); err != nil {
-			os.Exit(1)
-		}
+		serverErr <- serve.RunServer(c)
 	}()
 
+	srcDir := filepath.Join(cwd, config.Build.Source)
+
 	// Create new watcher.
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		fmt.Printf("Error creating watcher: %v\n", err)
-		return err
+		return fmt.Errorf("Error creating watcher: %w", err)
 	}
 	defer watcher.Close()
 
-	sig := make(chan os.Signal)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sig
+	debounce := util.NewDebouncer(250 * time.Millisecond)
 
-		fmt.Println("Shutting down server...")
+	// Files outside of the source directory that pages depend on, such as
+	// code snippets. Their directories are watched, so events need to be
+	// filtered down to the files themselves.
+	var dependenciesMu sync.Mutex
+	dependencies := map[string]bool{}
 
-		os.Exit(1)
-	}()
+	watchDependencies := func(files *[]build.DocFile) {
+		dependenciesMu.Lock()
+		defer dependenciesMu.Unlock()
 
-	debounce := util.NewDebouncer(250 * time.Millisecond)
+		for _, file := range *files {
+			for _, dependency := range file.Dependencies {
+				if dependencies[dependency] || strings.HasPrefix(dependency, srcDir) {
+					continue
+				}
+
+				if err := watcher.Add(filepath.Dir(dependency)); err != nil {
+					fmt.Printf("Error watching %s: %v\n", dependency, err)
+					continue
+				}
+
+				dependencies[dependency] = true
+			}
+		}
+	}
+
+	isDependency := func(path string) bool {
+		dependenciesMu.Lock()
+		defer dependenciesMu.Unlock()
+
+		return dependencies[path]
+	}
 
 	// Start listening for events.
 	go func() {
@@ -61,11 +88,29 @@ func Dev(c *cli.Context) error {
 				}
 				slog.Info("File system even detected", "even", event)
 
+				if !strings.HasPrefix(event.Name, srcDir) && !isDependency(event.Name) {
+					continue
+				}
+
+				if event.Has(fsnotify.Create) {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						if err := watchTree(watcher, event.Name); err != nil {
+							fmt.Printf("Error watching %s: %v\n", event.Name, err)
+						}
+					}
+				}
+
 				if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Write) {
 					debounce(func() {
-						if _, _, err := build.BuildAllFiles(c); err != nil {
+						files, _, err := build.BuildAllFiles(c)
+						if err != nil {
 							fmt.Printf("Error building: %v\n", err)
+							return
 						}
+
+						build.PrintWarnings(*files)
+
+						watchDependencies(files)
 					})
 				}
 			case err, ok := <-watcher.Errors:
@@ -79,14 +124,24 @@ func Dev(c *cli.Context) error {
 	}()
 
 	// Add a path.
-	err = watcher.Add(filepath.Join(cwd, config.Build.Source))
+	err = watchTree(watcher, srcDir)
 	if err != nil {
-		fmt.Printf("Error watching source directory: %v\n", err)
-		return err
+		return fmt.Errorf("Error watching source directory: %w", err)
 	}
 
-	// Block main goroutine forever.
-	<-make(chan struct{})
+	watchDependencies(files)
+
+	return <-serverErr
+}
+
+// watchTree watches the directory and all directories in it, since fsnotify
+// does not watch recursively.
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
 
-	return nil
+		return watcher.Add(path)
+	})
 }
@@ -0,0 +1,119 @@
+package docs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/clidocs"
+	"github.com/lukeshay/gocden/pkg/cmds"
+	"github.com/lukeshay/gocden/pkg/config"
+	cli "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// CLI writes a reference page for gocden and each of its commands. The pages
+// go to the cli directory in the source directory unless --out is set.
+func CLI(c *cli.Context) error {
+	cwd := cmds.GetCwdFlag(c)
+	conf := cmds.GetConfigFromCliContext(c)
+
+	outDir := c.String("out")
+	if outDir == "" {
+		outDir = filepath.Join(conf.Build.Source, "cli")
+	}
+	if !filepath.IsAbs(outDir) {
+		outDir = filepath.Join(cwd, outDir)
+	}
+
+	paths, err := clidocs.Write(c.App, outDir, c.String("section"))
+	if err != nil {
+		return fmt.Errorf("Error writing CLI docs: %w", err)
+	}
+
+	for _, path := range paths {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+
+	return nil
+}
+
+// Config writes the reference page of the config file and its JSON Schema.
+// The page goes to configuration.md in the source directory unless --out is
+// set, and the schema goes next to the config file unless --schema is set.
+func Config(c *cli.Context) error {
+	cwd := cmds.GetCwdFlag(c)
+	conf := cmds.GetConfigFromCliContext(c)
+
+	outPath := c.String("out")
+	if outPath == "" {
+		outPath = filepath.Join(conf.Build.Source, "configuration.md")
+	}
+	if !filepath.IsAbs(outPath) {
+		outPath = filepath.Join(cwd, outPath)
+	}
+
+	schemaPath := c.String("schema")
+	if !filepath.IsAbs(schemaPath) {
+		schemaPath = filepath.Join(cwd, schemaPath)
+	}
+
+	schema, err := config.Schema()
+	if err != nil {
+		return err
+	}
+
+	matter, err := yaml.Marshal(struct {
+		Title   string `yaml:"title"`
+		Section string `yaml:"section,omitempty"`
+	}{c.String("title"), c.String("section")})
+	if err != nil {
+		return err
+	}
+
+	schemaName := filepath.Base(schemaPath)
+	if rel, err := filepath.Rel(cwd, schemaPath); err == nil && !strings.HasPrefix(rel, "..") {
+		schemaName = filepath.ToSlash(rel)
+	}
+
+	var page strings.Builder
+
+	page.WriteString("---\n")
+	page.Write(matter)
+	page.WriteString("---\n\n")
+	fmt.Fprintf(&page, "# %s\n\n", c.String("title"))
+	fmt.Fprintf(&page, "gocden reads its configuration from `%s` in the directory of the site, or the nearest parent directory that has one. This page is generated by `gocden docs config`.\n\n", config.ConfigPath)
+	fmt.Fprintf(&page, "The config can also be written in YAML or JSON, as `%s`, with the same keys. A directory can only have one config file.\n\n", strings.Join(config.ConfigPaths[1:], "`, `"))
+	fmt.Fprintf(&page, "Editors that support JSON Schema, such as VS Code with Even Better TOML or the YAML extension, can complete and validate the config with `%s`:\n\n", schemaName)
+	page.WriteString(":::tabs key=config-format\n")
+	fmt.Fprintf(&page, "```toml title=\"TOML\"\n#:schema ./%s\nname = 'My docs'\n```\n\n", schemaName)
+	fmt.Fprintf(&page, "```yaml title=\"YAML\"\n# yaml-language-server: $schema=./%s\nname: My docs\n```\n\n", schemaName)
+	fmt.Fprintf(&page, "```json title=\"JSON\"\n{\n  \"$schema\": \"./%s\",\n  \"name\": \"My docs\"\n}\n```\n", schemaName)
+	page.WriteString(":::\n\n")
+	page.WriteString("## Environments\n\n")
+	page.WriteString("Sites that are built for more than one environment, such as staging and production, can override keys per environment. With `--env staging`, or `GOCDEN_ENV=staging`, the keys of `gocden.staging.toml` next to the config, or `gocden.staging.yaml` next to `gocden.yaml`, replace the keys of the config. Arrays are replaced as a whole and tables are merged.\n\n")
+	fmt.Fprintf(&page, "Environment variables replace both. The variable of a key is its name in upper case, with dots replaced by underscores and a `%s` prefix, such as `%s` for `url` and `%s` for `build.out`. Arrays of strings are separated by commas, and keys of `[[openapi]]` and `[links]` cannot be set.\n\n", config.EnvPrefix, config.EnvVar("url"), config.EnvVar("build.out"))
+	page.WriteString("`gocden config print` prints the resolved config and where each value comes from.\n\n")
+	page.WriteString(config.Reference())
+
+	for path, content := range map[string][]byte{outPath: []byte(page.String()), schemaPath: append(schema, '\n')} {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return fmt.Errorf("Error writing config reference: %w", err)
+		}
+
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			path = rel
+		}
+		fmt.Printf("Wrote %s\n", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/lukeshay/gocden/pkg/assets"
+	"github.com/lukeshay/gocden/pkg/cmds"
+	"github.com/lukeshay/gocden/pkg/cmds/build"
+	cli "github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+)
+
+const startersDir = "starters"
+
+// Starters returns the names of the starters that sites can be created from.
+func Starters() []string {
+	entries, _ := assets.Assets.ReadDir(startersDir)
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// starterFuncs quote values in the starter templates, such as the name of the
+// site in the config and frontmatter.
+var starterFuncs = template.FuncMap{
+	"toml": tomlString,
+	"yaml": yamlString,
+}
+
+// NewSite creates a site in the directory from a starter. The directory must
+// not exist or be empty. Files of the starter ending in .tmpl are executed as
+// templates with the name of the site, and quote it with the toml and yaml
+// functions.
+func NewSite(dir string, starter string, name string) ([]string, error) {
+	root := path.Join(startersDir, starter)
+	if info, err := fs.Stat(assets.Assets, root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("Unknown starter %q, expected one of: %s", starter, strings.Join(Starters(), ", "))
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	data := struct{ Name string }{name}
+	paths := []string{}
+
+	err := fs.WalkDir(assets.Assets, root, func(src string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := assets.Assets.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(src, root+"/")))
+
+		if strings.HasSuffix(dst, ".tmpl") {
+			dst = strings.TrimSuffix(dst, ".tmpl")
+
+			tmpl, err := template.New(path.Base(src)).Funcs(starterFuncs).Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("Could not parse starter file %s: %v", src, err)
+			}
+
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return fmt.Errorf("Could not execute starter file %s: %v", src, err)
+			}
+			content = buf.Bytes()
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(dst, content, 0644); err != nil {
+			return fmt.Errorf("Could not write file: %s", err.Error())
+		}
+
+		paths = append(paths, dst)
+
+		return nil
+	})
+
+	return paths, err
+}
+
+// tomlString quotes the text as a TOML basic string.
+func tomlString(text string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+	for _, r := range text {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// yamlString quotes the text as a YAML scalar if it needs to be quoted.
+func yamlString(text string) (string, error) {
+	out, err := yaml.Marshal(text)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// NewPage creates a page at the path in the source directory, with the title
+// and section in its frontmatter. The .md extension is optional. When
+// ordering is enabled, the page and any directory that is created for it get
+// the next free ordering prefix, and existing directories are matched without
+// their prefix, so guides/deploy can create 02-guides/03-deploy.md.
+func NewPage(srcDir string, pagePath string, title string, section string, ordering bool) (string, error) {
+	pagePath = strings.TrimSuffix(filepath.ToSlash(pagePath), ".md")
+
+	parts := strings.Split(strings.Trim(pagePath, "/"), "/")
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("Invalid page path %q", pagePath)
+		}
+	}
+
+	dir := srcDir
+
+	for idx, part := range parts {
+		last := idx == len(parts)-1
+
+		name := part
+		if last {
+			name += ".md"
+		}
+
+		existing, next, err := findEntry(dir, name, ordering)
+		if err != nil {
+			return "", err
+		}
+
+		if existing != "" {
+			if last {
+				return "", fmt.Errorf("The page %s already exists", filepath.Join(dir, existing))
+			}
+			dir = filepath.Join(dir, existing)
+			continue
+		}
+
+		if ordering {
+			if _, _, ok := build.OrderPrefix(name); !ok {
+				name = fmt.Sprintf("%02d-%s", next, name)
+			}
+		}
+
+		dir = filepath.Join(dir, name)
+	}
+
+	if title == "" {
+		title = titleFromName(parts[len(parts)-1])
+	}
+
+	matter, err := yaml.Marshal(struct {
+		Title   string `yaml:"title"`
+		Section string `yaml:"section,omitempty"`
+	}{title, section})
+	if err != nil {
+		return "", err
+	}
+
+	content := fmt.Sprintf("---\n%s---\n\n# %s\n", matter, title)
+
+	if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(dir, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("Could not write file: %s", err.Error())
+	}
+
+	return dir, nil
+}
+
+// findEntry returns the entry in the directory with the name, ignoring
+// ordering prefixes when ordering is enabled, and the next free ordering
+// prefix in the directory.
+func findEntry(dir string, name string, ordering bool) (string, int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", 1, nil
+	} else if err != nil {
+		return "", 0, err
+	}
+
+	_, wanted, _ := build.OrderPrefix(name)
+	existing := ""
+	next := 1
+
+	for _, entry := range entries {
+		number, stripped, ok := build.OrderPrefix(entry.Name())
+		if ok && number >= next {
+			next = number + 1
+		}
+
+		if entry.Name() == name || ordering && stripped == wanted {
+			existing = entry.Name()
+		}
+	}
+
+	return existing, next, nil
+}
+
+func titleFromName(name string) string {
+	_, name, _ = build.OrderPrefix(name)
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == ' '
+	})
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+
+	return strings.Join(words, " ")
+}
+
+// Site creates a new site from a starter in the directory given as the
+// argument.
+func Site(c *cli.Context) error {
+	args := cmds.Args(c)
+	if len(args) != 1 {
+		return fmt.Errorf("Expected the directory of the site, for example: gocden new site my-docs")
+	}
+
+	dir := args[0]
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cmds.GetCwdFlag(c), dir)
+	}
+
+	name := c.String("name")
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+
+	paths, err := NewSite(dir, c.String("starter"), name)
+	if err != nil {
+		return fmt.Errorf("Error creating site: %w", err)
+	}
+
+	for _, path := range paths {
+		if rel, err := filepath.Rel(dir, path); err == nil {
+			path = rel
+		}
+		fmt.Printf("Created %s\n", path)
+	}
+
+	fmt.Printf("\nYour site is ready! Run `gocden dev --cwd %s` to start writing.\n", args[0])
+
+	return nil
+}
+
+// Page creates a new page at the path given as the argument, relative to the
+// source directory.
+func Page(c *cli.Context) error {
+	args := cmds.Args(c)
+	if len(args) != 1 {
+		return fmt.Errorf("Expected the path of the page, for example: gocden new page guides/deploy")
+	}
+
+	cwd := cmds.GetCwdFlag(c)
+	conf := cmds.GetConfigFromCliContext(c)
+
+	path, err := NewPage(filepath.Join(cwd, conf.Build.Source), args[0], c.String("title"), c.String("section"), conf.Options.Ordering)
+	if err != nil {
+		return fmt.Errorf("Error creating page: %w", err)
+	}
+
+	if rel, err := filepath.Rel(cwd, path); err == nil {
+		path = rel
+	}
+	fmt.Printf("Created %s\n", path)
+
+	return nil
+}
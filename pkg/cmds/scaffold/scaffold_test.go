@@ -0,0 +1,53 @@
+package scaffold
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukeshay/gocden/pkg/cmds/build"
+	"github.com/lukeshay/gocden/pkg/config"
+)
+
+func TestNewSiteQuotesName(t *testing.T) {
+	name := `Bob's "Docs": v2 #1 \ API`
+
+	for _, starter := range Starters() {
+		t.Run(starter, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "site")
+
+			if _, err := NewSite(dir, starter, name); err != nil {
+				t.Fatalf("new site: %v", err)
+			}
+
+			conf, _, err := config.Read(filepath.Join(dir, config.ConfigPath), "")
+			if err != nil {
+				t.Fatalf("read config: %v", err)
+			}
+			if conf.Name != name {
+				t.Errorf("name is %q, expected %q", conf.Name, name)
+			}
+
+			site, err := build.ParseAllFiles(context.Background(), conf, dir)
+			if err != nil {
+				t.Fatalf("parse pages: %v", err)
+			}
+
+			titles := map[string]bool{}
+			for _, file := range site.Files {
+				titles[file.Matter.Title] = true
+			}
+
+			switch starter {
+			case "minimal":
+				if !titles[name] {
+					t.Errorf("no page has the title %q: %v", name, titles)
+				}
+			case "api":
+				if !titles[name+" API"] {
+					t.Errorf("no page has the title %q: %v", name+" API", titles)
+				}
+			}
+		})
+	}
+}
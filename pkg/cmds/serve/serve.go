@@ -1,103 +1,177 @@
 package serve
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
-	"os"
-	"os/signal"
+	"path"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/lukeshay/gocden/pkg/cmds"
+	"github.com/lukeshay/gocden/pkg/config"
 	"github.com/urfave/cli/v2"
 )
 
-type route struct {
-	pattern *regexp.Regexp
-	handler http.Handler
+// shutdownTimeout is how long requests that are being served when the server
+// is stopped have to finish.
+const shutdownTimeout = 10 * time.Second
+
+// Handler serves the files of the output directory under the base path, the
+// path of the url of the site. Paths without an extension are served from
+// their .html file, and directories from their index.html. Requests for the
+// base path without a trailing slash, or for / when the site has a base path,
+// are redirected to the base path, other requests outside of it are not found.
+type Handler struct {
+	BasePath string
+	OutDir   string
 }
 
-type RegexpHandler struct {
-	routes []*route
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.BasePath != "" && (r.URL.Path == h.BasePath || r.URL.Path == "/") {
+		http.Redirect(w, r, h.BasePath+"/", http.StatusFound)
+		return
+	}
+
+	filePath, ok := strings.CutPrefix(r.URL.Path, h.BasePath+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasSuffix(filePath, "/") || filePath == "" {
+		filePath += "index.html"
+	} else if !strings.Contains(path.Base(filePath), ".") {
+		filePath += ".html"
+	}
+
+	file := filepath.Join(h.OutDir, filepath.FromSlash(path.Clean("/"+filePath)))
+
+	slog.Info("Serving file", "path", r.URL.Path, "file", file)
+
+	http.ServeFile(w, r, file)
+}
+
+// sitePath returns the path of the url of the site without a trailing slash,
+// which is empty when the site is served from the root.
+func sitePath(conf *config.Config) string {
+	uri, err := url.Parse(conf.Url)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(uri.Path, "/")
 }
 
-func (h *RegexpHandler) Handler(pattern *regexp.Regexp, handler http.Handler) {
-	h.routes = append(h.routes, &route{pattern, handler})
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
 }
 
-func (h *RegexpHandler) HandleFunc(pattern *regexp.Regexp, handler func(http.ResponseWriter, *http.Request)) {
-	h.routes = append(h.routes, &route{pattern, http.HandlerFunc(handler)})
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
 }
 
-func (h *RegexpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	for _, route := range h.routes {
-		if route.pattern.MatchString(r.URL.Path) {
-			route.handler.ServeHTTP(w, r)
-			return
-		}
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
 	}
 
-	http.NotFound(w, r)
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+
+	return n, err
+}
+
+// accessLog prints a line for each request with its status, the size of the
+// response and how long it took.
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+
+		fmt.Printf("%s %s %s %d %d %s\n", start.Format("2006-01-02 15:04:05"), r.Method, r.URL.RequestURI(), recorder.status, recorder.size, time.Since(start).Round(time.Microsecond))
+	})
 }
 
+// RunServer serves the output directory until the context of c is canceled,
+// then stops accepting requests and waits for the ones being served.
 func RunServer(c *cli.Context) error {
 	cwd := cmds.GetCwdFlag(c)
-	config := cmds.GetConfigFromCliContext(c)
-
-	basePath := ""
-	if uri, err := url.Parse(config.Url); err == nil {
-		if strings.HasSuffix(uri.Path, "/") {
-			basePath = uri.Path[:len(uri.Path)-1]
-		} else {
-			basePath = uri.Path
-		}
+	conf := cmds.GetConfigFromCliContext(c)
+
+	basePath := sitePath(conf)
+
+	var handler http.Handler = &Handler{
+		BasePath: basePath,
+		OutDir:   filepath.Join(cwd, conf.Build.Output),
 	}
 
-	handler := &RegexpHandler{}
+	if conf.Serve.AccessLog {
+		handler = accessLog(handler)
+	}
 
-	pathRegExp := regexp.MustCompile(fmt.Sprintf("%s.*", basePath))
+	server := &http.Server{
+		Addr:              net.JoinHostPort(conf.Serve.Host, strconv.Itoa(conf.Serve.Port)),
+		Handler:           handler,
+		ReadTimeout:       time.Duration(conf.Serve.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(conf.Serve.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(conf.Serve.WriteTimeout) * time.Second,
+	}
 
-	handler.HandleFunc(pathRegExp, func(w http.ResponseWriter, r *http.Request) {
-		filePath := strings.Replace(r.URL.Path, basePath, "", 1)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("Error starting server: %w", err)
+	}
 
-		slog.Info("Serving request", "basePath", basePath, "path", r.URL.Path, "withoutBasePath", filePath)
+	host := conf.Serve.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
 
-		if strings.HasSuffix(filePath, "/") || filePath == "" {
-			filePath += "index.html"
-		} else if !strings.Contains(filePath, ".") {
-			filePath += ".html"
-		}
+	fmt.Printf("Listening on http://%s%s/ ...\n", net.JoinHostPort(host, strconv.Itoa(conf.Serve.Port)), basePath)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(listener)
+	}()
 
-		slog.Info("Serving file", "filePath", filePath, "file", filepath.Join(cwd, config.Build.Output, filePath))
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("Error serving: %v", err)
+	case <-c.Context.Done():
+	}
 
-		http.ServeFile(w, r, filepath.Join(cwd, config.Build.Output, filePath))
-	})
+	fmt.Println("Shutting down server...")
 
-	fmt.Printf("Listening on http://localhost:%d%s ...\n", config.Serve.Port, basePath)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	err := http.ListenAndServe(fmt.Sprintf(":%d", config.Serve.Port), handler)
-	if err != nil {
-		fmt.Println("Error starting server: %v", err)
-		return fmt.Errorf("Error starting server: %v", err)
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("Error shutting down server: %v", err)
+	}
+
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("Error serving: %v", err)
 	}
 
 	return nil
 }
 
 func Serve(c *cli.Context) error {
-	sig := make(chan os.Signal)
-	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-sig
-
-		fmt.Println("Shutting down server...")
-
-		os.Exit(1)
-	}()
-
 	return RunServer(c)
 }
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/hashicorp/go-multierror"
 	"github.com/lukeshay/gocden/pkg/validation"
 	"github.com/pelletier/go-toml/v2"
 )
@@ -12,93 +16,279 @@ import (
 const ConfigPath = "gocden.toml"
 
 type Social struct {
-	Twitter   string `toml:"twitter"`
-	Facebook  string `toml:"facebook"`
-	Instagram string `toml:"instagram"`
-	LinkedIn  string `toml:"linkedin"`
-	GitHub    string `toml:"github"`
-	GitLab    string `toml:"gitlab"`
-	Bitbucket string `toml:"bitbucket"`
+	Twitter   string `toml:"twitter" doc:"Twitter handle, used for the twitter:site meta tag"`
+	Facebook  string `toml:"facebook" doc:"Facebook page"`
+	Instagram string `toml:"instagram" doc:"Instagram account"`
+	LinkedIn  string `toml:"linkedin" doc:"LinkedIn page"`
+	GitHub    string `toml:"github" doc:"GitHub repository or account"`
+	GitLab    string `toml:"gitlab" doc:"GitLab repository or account"`
+	Bitbucket string `toml:"bitbucket" doc:"Bitbucket repository or account"`
 }
 
 type Build struct {
-	Source string `toml:"src" validate:"required"`
-	Output string `toml:"out" validate:"required"`
+	Source string `toml:"src" validate:"required" doc:"Directory with the markdown files, relative to the working directory"`
+	Output string `toml:"out" validate:"required" doc:"Directory the site is written to, relative to the working directory"`
 }
 
 type Options struct {
-	Ordering bool `toml:"ordering"`
+	Ordering bool `toml:"ordering" doc:"Strip numeric prefixes such as 01- from file names, which order the pages"`
 }
 
+type Highlight struct {
+	Style     string `toml:"style" doc:"Chroma style for code blocks"`
+	DarkStyle string `toml:"dark_style" doc:"Chroma style for code blocks in dark mode"`
+}
+
+type Markdown struct {
+	Footnote       bool `toml:"footnote" doc:"Enable footnotes"`
+	DefinitionList bool `toml:"definition_list" doc:"Enable definition lists"`
+	Typographer    bool `toml:"typographer" doc:"Replace quotes, dashes and ellipses with typographic ones"`
+	Emoji          bool `toml:"emoji" doc:"Replace emoji shortcodes such as :smile:"`
+	Unsafe         bool `toml:"unsafe" doc:"Render raw HTML in markdown"`
+	HardWraps      bool `toml:"hard_wraps" doc:"Render newlines in paragraphs as line breaks"`
+	Math           bool `toml:"math" doc:"Render TeX math between dollar signs"`
+}
+
+// GoDoc generates API reference pages for Go packages. Packages are
+// directories relative to the working directory, and a package ending in /...
+// includes the packages in it.
+type GoDoc struct {
+	Packages []string `toml:"packages" doc:"Package directories, relative to the working directory; a trailing /... includes the packages in them"`
+	Section  string   `toml:"section" doc:"Nav section of the pages"`
+	Path     string   `toml:"path" doc:"Output directory of the pages, relative to the output directory"`
+}
+
+// OpenAPI generates reference pages for an OpenAPI 3 specification, which is
+// a YAML or JSON file relative to the working directory. GroupBy is tag for
+// a page per tag or operation for a page per operation.
+type OpenAPI struct {
+	Spec    string `toml:"spec" validate:"required" doc:"YAML or JSON specification, relative to the working directory"`
+	Section string `toml:"section" doc:"Nav section of the pages, the title of the specification by default"`
+	Path    string `toml:"path" doc:"Output directory of the pages, the name of the specification file by default"`
+	GroupBy string `toml:"group_by" validate:"omitempty,oneof=tag operation" doc:"Create a page per tag or per operation"`
+}
+
+// Check configures gocden check. Rules maps the names of rules to their
+// severity, which is error, warning, info or off.
+type Check struct {
+	FailOn string            `toml:"fail_on" validate:"omitempty,oneof=error warning info never" doc:"Lowest severity of the findings that fail the check, or never"`
+	Rules  map[string]string `toml:"rules" validate:"dive,oneof=error warning info off" doc:"Severities of rules by name, error, warning, info or off"`
+}
+
+// Serve configures the serve and dev servers. Timeouts are in seconds, and 0
+// is no limit.
 type Serve struct {
-	Port int `toml:"port"`
+	Host         string `toml:"host" doc:"Host the servers listen on, such as 0.0.0.0 for all interfaces"`
+	Port         int    `toml:"port" doc:"Port of the serve and dev servers"`
+	ReadTimeout  int    `toml:"read_timeout" validate:"gte=0" doc:"Seconds to read a request, including its body, or 0 for no limit"`
+	WriteTimeout int    `toml:"write_timeout" validate:"gte=0" doc:"Seconds to write a response, or 0 for no limit"`
+	AccessLog    bool   `toml:"access_log" doc:"Print a line for each request"`
 }
 
 type Config struct {
-	Name        string   `toml:"name" validate:"required"`
-	Description string   `toml:"description"`
-	Url         string   `toml:"url"`
-	Social      *Social  `toml:"social"`
-	Build       *Build   `toml:"build"`
-	Options     *Options `toml:"options"`
-	Serve       *Serve   `toml:"serve"`
+	Name        string            `toml:"name" validate:"required" doc:"Name of the site"`
+	Description string            `toml:"description" doc:"Description of the site, used for meta tags"`
+	Url         string            `toml:"url" doc:"URL the site is served from; its path is the base path of links"`
+	Social      *Social           `toml:"social" doc:"Social accounts of the site"`
+	Build       *Build            `toml:"build" doc:"Source and output directories"`
+	Options     *Options          `toml:"options" doc:"Build options"`
+	Markdown    *Markdown         `toml:"markdown" doc:"Markdown extensions"`
+	Highlight   *Highlight        `toml:"highlight" doc:"Code highlighting"`
+	Serve       *Serve            `toml:"serve" doc:"Development and preview server"`
+	GoDoc       *GoDoc            `toml:"godoc,omitempty" doc:"API reference pages for Go packages"`
+	OpenAPI     []*OpenAPI        `toml:"openapi,omitempty" validate:"dive" doc:"Reference pages for OpenAPI 3 specifications"`
+	Links       map[string]string `toml:"links,omitempty" doc:"Links for names in code blocks, such as a type to its docs"`
+	Check       *Check            `toml:"check,omitempty" doc:"Content checks of gocden check"`
 }
 
-func ReadAndValidateOrCreate(wd string) (*Config, error) {
-	file, err := os.ReadFile(filepath.Join(wd, ConfigPath))
-
-	config := &Config{
+// Default returns the values of the keys that a config file does not set.
+func Default() *Config {
+	return &Config{
+		Social: &Social{},
+		Build:  &Build{},
 		Options: &Options{
 			Ordering: true,
 		},
+		Markdown: &Markdown{},
+		Highlight: &Highlight{
+			Style:     "github",
+			DarkStyle: "dracula",
+		},
 		Serve: &Serve{
-			Port: 7153,
+			Host:         "localhost",
+			Port:         7153,
+			ReadTimeout:  10,
+			WriteTimeout: 30,
+			AccessLog:    true,
+		},
+		GoDoc: &GoDoc{
+			Section: "API Reference",
+			Path:    "api",
+		},
+		Check: &Check{
+			FailOn: "error",
 		},
 	}
+}
 
-	if err != nil {
-		config = &Config{
-			Name:        filepath.Base(wd),
-			Description: "A new gocden site",
-			Url:         "",
-			Social: &Social{
-				GitHub:    "",
-				Twitter:   "",
-				Facebook:  "",
-				Instagram: "",
-				LinkedIn:  "",
-				GitLab:    "",
-				Bitbucket: "",
-			},
-			Options: &Options{
-				Ordering: true,
-			},
-			Build: &Build{
-				Source: "docs",
-				Output: "dist",
-			},
-			Serve: &Serve{
-				Port: 7153,
-			},
+// ErrNotFound is returned by Find when there is no config file.
+var ErrNotFound = errors.New("config file not found")
+
+// Find returns the path of the config file of the directory, which is the
+// nearest one in the directory or one of its parents, so commands work from
+// any directory of a site. It fails if a directory has more than one config
+// file, as it is not clear which one is meant.
+func Find(dir string) (string, error) {
+	for current := dir; ; current = filepath.Dir(current) {
+		found := []string{}
+
+		for _, name := range ConfigPaths {
+			path := filepath.Join(current, name)
+
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				found = append(found, path)
+			} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+
+		if len(found) == 1 {
+			return found[0], nil
+		} else if len(found) > 1 {
+			names := []string{}
+			for _, path := range found {
+				names = append(names, filepath.Base(path))
+			}
+
+			return "", fmt.Errorf("%s has more than one config file, %s, remove all but one or choose one with --config", current, strings.Join(names, " and "))
+		}
+
+		if current == filepath.Dir(current) {
+			return "", fmt.Errorf("No %s in %s or its parent directories, run gocden init to create one: %w", ConfigPath, dir, ErrNotFound)
 		}
+	}
+}
+
+// Read reads and validates the config file, which is a toml, YAML or JSON
+// file. Keys it does not set keep the values of Default. The file of env,
+// such as gocden.staging.toml, overrides it, and the GOCDEN_ environment
+// variables of keys, such as GOCDEN_BUILD_OUT, override both.
+func Read(path string, env string) (*Config, Sources, error) {
+	config := Default()
+	sources := Sources{}
 
-		jsonConfig, err := toml.Marshal(*config)
+	paths := []string{path}
+	if env != "" {
+		ext := filepath.Ext(path)
+		paths = append(paths, strings.TrimSuffix(path, ext)+"."+env+ext)
+	}
+
+	// the contents of the files, to find the lines of invalid keys in
+	contents := map[string][]byte{}
+
+	for idx, path := range paths {
+		file, err := os.ReadFile(path)
+		if idx > 0 && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("There is no config for the %s environment, expected %s", env, path)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("Could not read config: %s", err.Error())
+		}
+
+		contents[filepath.Base(path)] = file
+
+		tomlFile, err := toTOML(path, file)
+		if err == nil {
+			err = readLayer(config, sources, filepath.Base(path), tomlFile)
+		}
 		if err != nil {
-			return config, err
+			return config, sources, fmt.Errorf("Your config is invalid: %s: %s", filepath.Base(path), err.Error())
+		}
+	}
+
+	if err := readEnv(config, sources); err != nil {
+		return config, sources, fmt.Errorf("Your config is invalid: %s", err.Error())
+	}
+
+	var result *multierror.Error
+
+	for _, err := range validation.Validate(config, "toml", "config key") {
+		err.File = filepath.Base(path)
+
+		// the key is in the file or environment variable it was set by, or
+		// in the config file if it is missing
+		for key := err.Key; key != ""; key = validation.ParentKey(key) {
+			if source, ok := sources[key]; ok {
+				err.File = source
+				break
+			}
 		}
 
-		if err = os.WriteFile(filepath.Join(wd, ConfigPath), jsonConfig, 0644); err != nil {
-			return config, fmt.Errorf("Could not write file: %s", err.Error())
+		if content, ok := contents[err.File]; ok {
+			err.Line = validation.Line(content, format(err.File), 1, err.Key)
 		}
-	} else {
-		if err := toml.Unmarshal(file, config); err != nil {
-			return config, fmt.Errorf("Your config is invalid: %s", err.Error())
+
+		result = multierror.Append(result, err)
+	}
+
+	return config, sources, result.ErrorOrNil()
+}
+
+// Create writes a new config file to the directory, named after it. It fails
+// if the directory already has a config file.
+func Create(dir string) (*Config, error) {
+	for _, name := range ConfigPaths {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return nil, fmt.Errorf("%s already exists", filepath.Join(dir, name))
 		}
 	}
 
-	if err := validation.ValidateAndPrint("Your config is invalid", config); err != nil {
+	path := filepath.Join(dir, ConfigPath)
+
+	config := &Config{
+		Name:        filepath.Base(dir),
+		Description: "A new gocden site",
+		Url:         "",
+		Social: &Social{
+			GitHub:    "",
+			Twitter:   "",
+			Facebook:  "",
+			Instagram: "",
+			LinkedIn:  "",
+			GitLab:    "",
+			Bitbucket: "",
+		},
+		Options: &Options{
+			Ordering: true,
+		},
+		Markdown: &Markdown{
+			Footnote:       true,
+			DefinitionList: true,
+		},
+		Build: &Build{
+			Source: "docs",
+			Output: "dist",
+		},
+		Highlight: &Highlight{
+			Style:     "github",
+			DarkStyle: "dracula",
+		},
+		Serve: &Serve{
+			Host:         "localhost",
+			Port:         7153,
+			ReadTimeout:  10,
+			WriteTimeout: 30,
+			AccessLog:    true,
+		},
+	}
+
+	tomlConfig, err := toml.Marshal(*config)
+	if err != nil {
 		return config, err
 	}
 
+	if err = os.WriteFile(path, tomlConfig, 0644); err != nil {
+		return config, fmt.Errorf("Could not write file: %s", err.Error())
+	}
+
 	return config, nil
 }
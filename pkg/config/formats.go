@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/data"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigPaths are the names of the config files that are found in the
+// directory of a site. Only one of them can be in a directory.
+var ConfigPaths = []string{ConfigPath, "gocden.yaml", "gocden.yml", "gocden.json"}
+
+// toTOML returns the config file as toml, so YAML and JSON config files are
+// read the same way as toml ones. The format is detected by the extension of
+// the file.
+func toTOML(path string, file []byte) ([]byte, error) {
+	var values interface{}
+
+	switch format(path) {
+	case "toml":
+		return file, nil
+	case "yaml":
+		if err := yaml.Unmarshal(file, &values); err != nil {
+			return nil, err
+		}
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(file))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected .toml, .yaml, .yml or .json", filepath.Ext(path))
+	}
+
+	if values == nil {
+		return []byte{}, nil
+	}
+
+	table, ok := jsonNumbers(data.Normalize(values)).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected keys and values, got %T", values)
+	}
+
+	return toml.Marshal(table)
+}
+
+// format returns the format of the config file, which is toml, yaml or json,
+// from its extension.
+func format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return "toml"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	}
+
+	return ""
+}
+
+// jsonNumbers replaces the numbers of a JSON file with integers where they
+// are whole, as integer keys cannot be decoded from floats.
+func jsonNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = jsonNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = jsonNumbers(child)
+		}
+	case json.Number:
+		if number, err := v.Int64(); err == nil {
+			return number
+		}
+		number, _ := v.Float64()
+		return number
+	}
+
+	return value
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Key is a key of the config file, read from the toml, validate and doc tags
+// of the config structs.
+type Key struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+	Enum        []string
+	// Default is nil if the key has no default.
+	Default interface{}
+	// Keys are the keys of a table or of the tables in an array.
+	Keys []Key
+}
+
+// Keys returns the keys of the config file with their defaults.
+func Keys() []Key {
+	return structKeys(reflect.TypeOf(Config{}), reflect.ValueOf(*Default()))
+}
+
+func structKeys(t reflect.Type, defaults reflect.Value) []Key {
+	keys := []Key{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := Key{
+			Name:        name,
+			Description: field.Tag.Get("doc"),
+		}
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "required" {
+				key.Required = true
+			} else if strings.HasPrefix(rule, "oneof=") {
+				key.Enum = strings.Fields(strings.TrimPrefix(rule, "oneof="))
+			}
+		}
+
+		var value reflect.Value
+		if defaults.IsValid() {
+			value = defaults.Field(i)
+		}
+
+		fieldType := field.Type
+
+		switch {
+		case fieldType.Kind() == reflect.Pointer && fieldType.Elem().Kind() == reflect.Struct:
+			key.Type = "table"
+			if value.IsValid() && !value.IsNil() {
+				value = value.Elem()
+			} else {
+				value = reflect.Value{}
+			}
+			key.Keys = structKeys(fieldType.Elem(), value)
+		case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Pointer && fieldType.Elem().Elem().Kind() == reflect.Struct:
+			key.Type = "array of tables"
+			key.Keys = structKeys(fieldType.Elem().Elem(), reflect.Value{})
+		case fieldType.Kind() == reflect.Slice:
+			key.Type = "array of " + scalarType(fieldType.Elem().Kind()) + "s"
+		case fieldType.Kind() == reflect.Map:
+			key.Type = "table of " + scalarType(fieldType.Elem().Kind()) + "s"
+		default:
+			key.Type = scalarType(fieldType.Kind())
+			if value.IsValid() && (fieldType.Kind() == reflect.Bool || !value.IsZero()) {
+				key.Default = value.Interface()
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func scalarType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	}
+
+	return "string"
+}
+
+// Schema returns a JSON Schema of the config file, which editors can use to
+// complete and validate it.
+func Schema() ([]byte, error) {
+	schema := objectSchema(Keys())
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	// JSON config files point to the schema with a $schema key
+	schema["properties"].(map[string]interface{})["$schema"] = map[string]interface{}{"type": "string"}
+	schema["title"] = ConfigPath
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func objectSchema(keys []Key) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for _, key := range keys {
+		properties[key.Name] = keySchema(key)
+		if key.Required {
+			required = append(required, key.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema
+}
+
+func keySchema(key Key) map[string]interface{} {
+	var schema map[string]interface{}
+
+	switch key.Type {
+	case "table":
+		schema = objectSchema(key.Keys)
+	case "array of tables":
+		schema = map[string]interface{}{"type": "array", "items": objectSchema(key.Keys)}
+	default:
+		if element, ok := strings.CutPrefix(key.Type, "array of "); ok {
+			schema = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": jsonType(strings.TrimSuffix(element, "s"))}}
+		} else if element, ok := strings.CutPrefix(key.Type, "table of "); ok {
+			schema = map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": jsonType(strings.TrimSuffix(element, "s"))}}
+		} else {
+			schema = map[string]interface{}{"type": jsonType(key.Type)}
+		}
+	}
+
+	if key.Description != "" {
+		schema["description"] = key.Description
+	}
+	if key.Default != nil {
+		schema["default"] = key.Default
+	}
+	if len(key.Enum) > 0 {
+		schema["enum"] = key.Enum
+	}
+	if key.Required && key.Type == "string" {
+		schema["minLength"] = 1
+	}
+
+	return schema
+}
+
+func jsonType(tomlType string) string {
+	if tomlType == "float" {
+		return "number"
+	}
+
+	return tomlType
+}
+
+// Reference returns a markdown reference of the config file, with a table of
+// keys for the top level and for each table.
+func Reference() string {
+	var md strings.Builder
+
+	keys := Keys()
+
+	md.WriteString("## Top-level keys\n\n")
+	keyTable(&md, "", keys)
+
+	var tables func(prefix string, keys []Key)
+	tables = func(prefix string, keys []Key) {
+		for _, key := range keys {
+			if len(key.Keys) == 0 {
+				continue
+			}
+
+			name := prefix + key.Name
+			if key.Type == "array of tables" {
+				fmt.Fprintf(&md, "## `[[%s]]`\n\n", name)
+			} else {
+				fmt.Fprintf(&md, "## `[%s]`\n\n", name)
+			}
+
+			if key.Description != "" {
+				fmt.Fprintf(&md, "%s.\n\n", key.Description)
+			}
+
+			keyTable(&md, name+".", key.Keys)
+			tables(name+".", key.Keys)
+		}
+	}
+
+	tables("", keys)
+
+	return md.String()
+}
+
+func keyTable(md *strings.Builder, prefix string, keys []Key) {
+	md.WriteString("| Key | Type | Default | Description |\n")
+	md.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, key := range keys {
+		name := "`" + key.Name + "`"
+		if len(key.Keys) > 0 {
+			if key.Type == "array of tables" {
+				name = fmt.Sprintf("[`%s`](#%s)", key.Name, anchor("[["+prefix+key.Name+"]]"))
+			} else {
+				name = fmt.Sprintf("[`%s`](#%s)", key.Name, anchor("["+prefix+key.Name+"]"))
+			}
+		}
+
+		description := key.Description
+		if len(key.Enum) > 0 {
+			description += ", one of `" + strings.Join(key.Enum, "`, `") + "`"
+		}
+		if key.Required {
+			description = "**Required.** " + description
+		}
+
+		defaultText := ""
+		if key.Default != nil {
+			encoded, _ := json.Marshal(key.Default)
+			defaultText = "`" + string(encoded) + "`"
+		}
+
+		fmt.Fprintf(md, "| %s | %s | %s | %s |\n", name, key.Type, defaultText, strings.ReplaceAll(description, "|", "\\|"))
+	}
+
+	md.WriteString("\n")
+}
+
+// anchor returns the id that headings get for the text.
+func anchor(text string) string {
+	var id strings.Builder
+
+	for _, r := range strings.ToLower(text) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			id.WriteRune(r)
+		}
+	}
+
+	return id.String()
+}
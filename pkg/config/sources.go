@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// EnvPrefix is the prefix of the environment variables of keys.
+const EnvPrefix = "GOCDEN_"
+
+// Sources are where the values of a config come from, by key, such as
+// build.out. The source is the name of a config file or of an environment
+// variable. Keys that are not in Sources have their default value.
+type Sources map[string]string
+
+// Of returns the source of the key.
+func (s Sources) Of(key string) string {
+	if source, ok := s[key]; ok {
+		return source
+	}
+
+	return "default"
+}
+
+func (s Sources) add(prefix string, values map[string]interface{}, source string) {
+	for key, value := range values {
+		if table, ok := value.(map[string]interface{}); ok {
+			s.add(prefix+key+".", table, source)
+		} else {
+			s[prefix+key] = source
+		}
+	}
+}
+
+// EnvVar returns the environment variable of the key, such as GOCDEN_BUILD_OUT
+// for build.out.
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// readLayer reads the toml into the config, replacing the values that are
+// already in it.
+func readLayer(config *Config, sources Sources, source string, data []byte) error {
+	values := map[string]interface{}{}
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	clearArrays(reflect.ValueOf(config).Elem(), values)
+
+	if err := toml.Unmarshal(data, config); err != nil {
+		return err
+	}
+
+	sources.add("", values, source)
+
+	return nil
+}
+
+// clearArrays empties the arrays of the struct that are in the values. Arrays
+// are appended to when toml is decoded into them, but a layer replaces them.
+func clearArrays(v reflect.Value, values map[string]interface{}) {
+	for key, value := range values {
+		field := fieldByTag(v, key)
+		if !field.IsValid() {
+			continue
+		}
+
+		switch value := value.(type) {
+		case []interface{}:
+			if field.Kind() == reflect.Slice {
+				field.Set(reflect.Zero(field.Type()))
+			}
+		case map[string]interface{}:
+			if field.Kind() == reflect.Pointer && !field.IsNil() && field.Elem().Kind() == reflect.Struct {
+				clearArrays(field.Elem(), value)
+			}
+		}
+	}
+}
+
+// readEnv reads the GOCDEN_ environment variables of the keys into the
+// config. Arrays of strings are separated by commas. Keys in arrays of tables
+// and in tables of values cannot be set.
+func readEnv(config *Config, sources Sources) error {
+	values := map[string]interface{}{}
+	envSources := Sources{}
+
+	var walk func(prefix string, keys []Key, values map[string]interface{}) error
+	walk = func(prefix string, keys []Key, values map[string]interface{}) error {
+		for _, key := range keys {
+			path := prefix + key.Name
+
+			if key.Type == "table" {
+				table := map[string]interface{}{}
+				if err := walk(path+".", key.Keys, table); err != nil {
+					return err
+				}
+				if len(table) > 0 {
+					values[key.Name] = table
+				}
+				continue
+			}
+
+			name := EnvVar(path)
+
+			text, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+
+			var value interface{}
+			var err error
+
+			switch key.Type {
+			case "string":
+				value = text
+			case "boolean":
+				value, err = strconv.ParseBool(text)
+			case "integer":
+				value, err = strconv.ParseInt(text, 10, 64)
+			case "float":
+				value, err = strconv.ParseFloat(text, 64)
+			case "array of strings":
+				items := []string{}
+				for _, item := range strings.Split(text, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+				value = items
+			default:
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a valid %s", name, text, key.Type)
+			}
+
+			values[key.Name] = value
+			envSources[path] = name
+		}
+
+		return nil
+	}
+
+	if err := walk("", Keys(), values); err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	data, err := toml.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	if err := readLayer(config, Sources{}, "", data); err != nil {
+		return err
+	}
+
+	for key, source := range envSources {
+		sources[key] = source
+	}
+
+	return nil
+}
+
+// Format returns the config as toml, with the source of each value in a
+// comment.
+func Format(config *Config, sources Sources) string {
+	var out strings.Builder
+
+	formatTable(&out, "", Keys(), reflect.ValueOf(config).Elem(), sources, "")
+
+	return strings.TrimLeft(out.String(), "\n")
+}
+
+// formatTable writes the keys of the table, and then its tables. The keys get
+// the source of the table when source is set, which is the case for the
+// tables of an array, as they are set as a whole.
+func formatTable(out *strings.Builder, prefix string, keys []Key, v reflect.Value, sources Sources, source string) {
+	sourceOf := func(path string) string {
+		if source != "" {
+			return source
+		}
+		return sources.Of(path)
+	}
+
+	for _, key := range keys {
+		if len(key.Keys) > 0 || strings.HasPrefix(key.Type, "table of ") {
+			continue
+		}
+
+		fmt.Fprintf(out, "%s = %s # %s\n", key.Name, formatValue(fieldByTag(v, key.Name)), sourceOf(prefix+key.Name))
+	}
+
+	for _, key := range keys {
+		value := fieldByTag(v, key.Name)
+		path := prefix + key.Name
+
+		switch {
+		case key.Type == "table":
+			if value.IsNil() {
+				continue
+			}
+
+			fmt.Fprintf(out, "\n[%s]\n", path)
+			formatTable(out, path+".", key.Keys, value.Elem(), sources, source)
+		case key.Type == "array of tables":
+			for i := 0; i < value.Len(); i++ {
+				if value.Index(i).IsNil() {
+					continue
+				}
+
+				fmt.Fprintf(out, "\n[[%s]]\n", path)
+				formatTable(out, path+".", key.Keys, value.Index(i).Elem(), sources, sourceOf(path))
+			}
+		case strings.HasPrefix(key.Type, "table of "):
+			if value.Len() == 0 {
+				continue
+			}
+
+			fmt.Fprintf(out, "\n[%s]\n", path)
+
+			names := []string{}
+			for _, name := range value.MapKeys() {
+				names = append(names, name.String())
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				fmt.Fprintf(out, "%s = %s # %s\n", formatKey(name), formatValue(value.MapIndex(reflect.ValueOf(name))), sourceOf(path+"."+name))
+			}
+		}
+	}
+}
+
+var bareKeyRegExp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func formatKey(key string) string {
+	if bareKeyRegExp.MatchString(key) {
+		return key
+	}
+
+	return formatValue(reflect.ValueOf(key))
+}
+
+// formatValue returns the value as toml. The values of config keys are
+// strings, numbers, booleans and arrays of them, for which json is valid
+// toml.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Slice && v.Len() == 0 {
+		return "[]"
+	}
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(v.Interface()); err != nil {
+		return `""`
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// fieldByTag returns the field of the struct with the toml key.
+func fieldByTag(v reflect.Value, key string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ","); name == key {
+			return v.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
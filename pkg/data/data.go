@@ -0,0 +1,147 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v2"
+)
+
+// Dir is the directory in the source directory that holds the data files.
+const Dir = "_data"
+
+// Load reads the JSON, TOML and YAML files in the directory into a tree. Each
+// file is stored under its name without the extension, inside the maps of
+// the directories it is in, so _data/compat/go.yaml is compat.go. The
+// directory does not have to exist.
+func Load(dir string) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return tree, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".json" && ext != ".toml" && ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		value, err := readFile(path, ext)
+		if err != nil {
+			return fmt.Errorf("Could not parse data file %s: %v", rel, err)
+		}
+
+		keys := strings.Split(strings.TrimSuffix(filepath.ToSlash(rel), filepath.Ext(rel)), "/")
+
+		parent := tree
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				if _, exists := parent[key]; exists {
+					return fmt.Errorf("Data file %s conflicts with the directory %s", rel, key)
+				}
+				child = map[string]interface{}{}
+				parent[key] = child
+			}
+			parent = child
+		}
+
+		key := keys[len(keys)-1]
+		if _, exists := parent[key]; exists {
+			return fmt.Errorf("Data file %s conflicts with another file or directory named %s", rel, key)
+		}
+		parent[key] = value
+
+		return nil
+	})
+
+	return tree, err
+}
+
+func readFile(path string, ext string) (interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(content, &value)
+	case ".toml":
+		err = toml.Unmarshal(content, &value)
+	default:
+		err = yaml.Unmarshal(content, &value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Normalize(value), nil
+}
+
+// Normalize converts the maps decoded from YAML, which have interface keys,
+// to maps with string keys like the ones decoded from JSON and TOML.
+func Normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			m[fmt.Sprint(key)] = Normalize(child)
+		}
+		return m
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = Normalize(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = Normalize(child)
+		}
+		return v
+	}
+
+	return value
+}
+
+// Lookup returns the value at a dotted path such as compat.go.versions, or
+// nil if there is none. Elements of lists are selected by their index.
+func Lookup(tree map[string]interface{}, path string) interface{} {
+	var value interface{} = tree
+
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscan(key, &i); err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
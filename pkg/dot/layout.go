@@ -0,0 +1,612 @@
+package dot
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	nodeSep    = 24.0
+	rankSep    = 48.0
+	charWidth  = 7.5
+	lineHeight = 18.0
+	// orderIterations is the number of sweeps used to reduce edge crossings
+	orderIterations = 24
+	// positionIterations is the number of sweeps used to straighten edges
+	positionIterations = 8
+)
+
+type point struct {
+	x, y float64
+}
+
+type layoutNode struct {
+	rank    int
+	order   int
+	x, y    float64
+	width   float64
+	height  float64
+	virtual bool
+	shape   string
+	lines   []string
+	loop    bool
+}
+
+type layoutEdge struct {
+	reversed bool
+	flat     bool
+	chain    []*Node
+	points   []point
+	label    point
+}
+
+// layout places the nodes in layers with the Sugiyama method: cycles are
+// broken, nodes are ranked by longest path, long edges are split by virtual
+// nodes, crossings are reduced with the barycenter heuristic and the nodes
+// are moved towards their neighbors to straighten edges.
+type layout struct {
+	g          *Graph
+	layers     [][]*Node
+	up         map[*Node][]*Node
+	down       map[*Node][]*Node
+	horizontal bool
+	rankSep    float64
+}
+
+func newLayout(g *Graph) *layout {
+	l := &layout{
+		g:       g,
+		up:      map[*Node][]*Node{},
+		down:    map[*Node][]*Node{},
+		rankSep: rankSep,
+	}
+
+	rankDir := strings.ToUpper(g.Attrs["rankdir"])
+	l.horizontal = rankDir == "LR" || rankDir == "RL"
+
+	for _, n := range g.Nodes {
+		n.shape = strings.ToLower(n.Attrs["shape"])
+		if n.shape == "" {
+			n.shape = "ellipse"
+		}
+		n.lines = labelLines(n.Attrs["label"], n.ID, g.ID)
+		n.width, n.height = nodeSize(n)
+	}
+
+	for _, e := range g.Edges {
+		if e.Attrs["label"] != "" {
+			l.rankSep += lineHeight
+			break
+		}
+	}
+
+	l.rank()
+	l.addVirtualNodes()
+	l.order()
+	l.position()
+	l.route()
+
+	return l
+}
+
+func labelLines(label string, id string, graphID string) []string {
+	if label == "" {
+		label = `\N`
+	}
+
+	label = strings.ReplaceAll(label, `\N`, id)
+	label = strings.ReplaceAll(label, `\G`, graphID)
+
+	lines := strings.FieldsFunc(strings.NewReplacer(`\n`, "\n", `\l`, "\n", `\r`, "\n").Replace(label), func(r rune) bool {
+		return r == '\n'
+	})
+
+	return lines
+}
+
+func textSize(lines []string) (float64, float64) {
+	width := 0
+	for _, line := range lines {
+		if n := utf8.RuneCountInString(line); n > width {
+			width = n
+		}
+	}
+
+	return float64(width) * charWidth, float64(len(lines)) * lineHeight
+}
+
+func nodeSize(n *Node) (float64, float64) {
+	if n.shape == "record" || n.shape == "mrecord" {
+		label := strings.NewReplacer("{", "", "}", "").Replace(n.Attrs["label"])
+		if label != "" {
+			n.lines = strings.Split(label, "|")
+			for i := range n.lines {
+				n.lines[i] = strings.TrimSpace(n.lines[i])
+			}
+		}
+	}
+
+	tw, th := textSize(n.lines)
+	var w, h float64
+
+	switch n.shape {
+	case "point":
+		n.lines = nil
+		w, h = 8, 8
+	case "plaintext", "plain", "none", "underline":
+		w, h = tw+16, th+8
+	case "circle", "doublecircle":
+		w = math.Max(math.Max(tw+20, th+20), 36)
+		h = w
+	case "diamond":
+		w, h = math.Max(tw*1.6+32, 72), math.Max(th*1.6+20, 40)
+	case "ellipse", "oval":
+		w, h = math.Max(tw*1.3+24, 54), math.Max(th*1.3+12, 36)
+	default:
+		w, h = math.Max(tw+24, 54), math.Max(th+16, 36)
+		if n.shape == "square" {
+			w = math.Max(w, h)
+			h = w
+		}
+	}
+
+	// width and height are in inches
+	if width, err := strconv.ParseFloat(n.Attrs["width"], 64); err == nil && width*72 > w {
+		w = width * 72
+	}
+	if height, err := strconv.ParseFloat(n.Attrs["height"], 64); err == nil && height*72 > h {
+		h = height * 72
+	}
+
+	return w, h
+}
+
+// span is the size of the node along its layer.
+func (l *layout) span(n *Node) float64 {
+	if l.horizontal {
+		return n.height
+	}
+
+	return n.width
+}
+
+// depth is the size of the node across its layer.
+func (l *layout) depth(n *Node) float64 {
+	if l.horizontal {
+		return n.width
+	}
+
+	return n.height
+}
+
+// rank breaks cycles and assigns every node to a layer.
+func (l *layout) rank() {
+	g := l.g
+
+	rep := map[*Node]*Node{}
+	for _, n := range g.Nodes {
+		rep[n] = n
+	}
+	for _, group := range g.sameRank {
+		for _, n := range group {
+			rep[n] = rep[group[0]]
+		}
+	}
+
+	out := map[*Node][]*Edge{}
+	for _, e := range g.Edges {
+		if e.From == e.To {
+			e.From.loop = true
+			continue
+		}
+		if rep[e.From] == rep[e.To] {
+			e.flat = true
+			continue
+		}
+		out[rep[e.From]] = append(out[rep[e.From]], e)
+	}
+
+	// edges to a node that is still being visited close a cycle, they are
+	// reversed to make the graph acyclic
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[*Node]int{}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		state[n] = visiting
+		for _, e := range out[n] {
+			switch state[rep[e.To]] {
+			case visiting:
+				e.reversed = true
+			case unvisited:
+				visit(rep[e.To])
+			}
+		}
+		state[n] = visited
+	}
+	for _, n := range g.Nodes {
+		if rep[n] == n && state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	successors := map[*Node][]*Node{}
+	minLen := map[[2]*Node]int{}
+	inDegree := map[*Node]int{}
+	for _, e := range g.Edges {
+		if e.From == e.To || e.flat {
+			continue
+		}
+		from, to := rep[e.From], rep[e.To]
+		if e.reversed {
+			from, to = to, from
+		}
+		length := 1
+		if value, err := strconv.Atoi(e.Attrs["minlen"]); err == nil && value >= 0 {
+			length = value
+		}
+		key := [2]*Node{from, to}
+		if _, ok := minLen[key]; !ok {
+			successors[from] = append(successors[from], to)
+			inDegree[to]++
+		}
+		if length > minLen[key] || minLen[key] == 0 {
+			minLen[key] = length
+		}
+	}
+
+	queue := []*Node{}
+	for _, n := range g.Nodes {
+		if rep[n] == n && inDegree[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+
+	ranks := map[*Node]int{}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, s := range successors[n] {
+			if r := ranks[n] + minLen[[2]*Node{n, s}]; r > ranks[s] {
+				ranks[s] = r
+			}
+			inDegree[s]--
+			if inDegree[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+
+	maxRank := 0
+	for _, n := range g.Nodes {
+		n.rank = ranks[rep[n]]
+		if n.rank > maxRank {
+			maxRank = n.rank
+		}
+	}
+
+	l.layers = make([][]*Node, maxRank+1)
+	for _, n := range g.Nodes {
+		l.layers[n.rank] = append(l.layers[n.rank], n)
+	}
+}
+
+// addVirtualNodes splits edges that span more than one layer.
+func (l *layout) addVirtualNodes() {
+	for _, e := range l.g.Edges {
+		if e.From == e.To || e.flat {
+			continue
+		}
+
+		from, to := e.From, e.To
+		if e.reversed {
+			from, to = to, from
+		}
+
+		e.chain = []*Node{from}
+		for r := from.rank + 1; r < to.rank; r++ {
+			v := &Node{layoutNode: layoutNode{rank: r, virtual: true}}
+			l.layers[r] = append(l.layers[r], v)
+			e.chain = append(e.chain, v)
+		}
+		e.chain = append(e.chain, to)
+
+		for i := 0; i+1 < len(e.chain); i++ {
+			a, b := e.chain[i], e.chain[i+1]
+			if a.rank == b.rank {
+				continue
+			}
+			l.down[a] = append(l.down[a], b)
+			l.up[b] = append(l.up[b], a)
+		}
+	}
+}
+
+// order reduces edge crossings by sorting the layers by the barycenter of the
+// neighbors in the previous layer, sweeping down and up.
+func (l *layout) order() {
+	l.updateOrder()
+
+	best := l.saveOrder()
+	bestCrossings := l.crossings()
+
+	for i := 0; i < orderIterations && bestCrossings > 0; i++ {
+		if i%2 == 0 {
+			for r := 1; r < len(l.layers); r++ {
+				l.sortLayer(r, l.up)
+			}
+		} else {
+			for r := len(l.layers) - 2; r >= 0; r-- {
+				l.sortLayer(r, l.down)
+			}
+		}
+
+		if crossings := l.crossings(); crossings < bestCrossings {
+			bestCrossings = crossings
+			best = l.saveOrder()
+		}
+	}
+
+	for r := range best {
+		l.layers[r] = best[r]
+	}
+	l.updateOrder()
+}
+
+func (l *layout) updateOrder() {
+	for _, layer := range l.layers {
+		for i, n := range layer {
+			n.order = i
+		}
+	}
+}
+
+func (l *layout) saveOrder() [][]*Node {
+	layers := make([][]*Node, len(l.layers))
+	for r, layer := range l.layers {
+		layers[r] = append([]*Node{}, layer...)
+	}
+
+	return layers
+}
+
+func (l *layout) sortLayer(r int, neighbors map[*Node][]*Node) {
+	layer := l.layers[r]
+	barycenters := map[*Node]float64{}
+
+	for _, n := range layer {
+		if len(neighbors[n]) == 0 {
+			barycenters[n] = float64(n.order)
+			continue
+		}
+		sum := 0.0
+		for _, m := range neighbors[n] {
+			sum += float64(m.order)
+		}
+		barycenters[n] = sum / float64(len(neighbors[n]))
+	}
+
+	sort.SliceStable(layer, func(i, j int) bool {
+		return barycenters[layer[i]] < barycenters[layer[j]]
+	})
+
+	for i, n := range layer {
+		n.order = i
+	}
+}
+
+func (l *layout) crossings() int {
+	count := 0
+
+	for r := 0; r+1 < len(l.layers); r++ {
+		edges := [][2]int{}
+		for _, n := range l.layers[r] {
+			for _, m := range l.down[n] {
+				edges = append(edges, [2]int{n.order, m.order})
+			}
+		}
+
+		for i := range edges {
+			for j := i + 1; j < len(edges); j++ {
+				a, b := edges[i], edges[j]
+				if (a[0] < b[0] && a[1] > b[1]) || (a[0] > b[0] && a[1] < b[1]) {
+					count++
+				}
+			}
+		}
+	}
+
+	return count
+}
+
+// position assigns coordinates. The layers are laid out top to bottom and
+// rotated afterwards for other rank directions.
+func (l *layout) position() {
+	along := map[*Node]float64{}
+	across := map[*Node]float64{}
+
+	offset := 0.0
+	for r, layer := range l.layers {
+		depth := 0.0
+		for _, n := range layer {
+			depth = math.Max(depth, l.depth(n))
+		}
+		if r > 0 {
+			offset += l.rankSep
+		}
+		for _, n := range layer {
+			across[n] = offset + depth/2
+		}
+		offset += depth
+
+		x := 0.0
+		for i, n := range layer {
+			if i > 0 {
+				x += l.span(layer[i-1])/2 + nodeSep + l.span(n)/2
+			}
+			along[n] = x
+		}
+	}
+
+	for i := 0; i < positionIterations; i++ {
+		for r := 1; r < len(l.layers); r++ {
+			l.align(l.layers[r], along, l.up)
+		}
+		for r := len(l.layers) - 2; r >= 0; r-- {
+			l.align(l.layers[r], along, l.down)
+		}
+	}
+
+	minAlong := math.Inf(1)
+	for _, layer := range l.layers {
+		for _, n := range layer {
+			minAlong = math.Min(minAlong, along[n]-l.span(n)/2)
+		}
+	}
+
+	maxAlong, maxAcross := 0.0, offset
+	for _, layer := range l.layers {
+		for _, n := range layer {
+			along[n] -= minAlong
+			maxAlong = math.Max(maxAlong, along[n]+l.span(n)/2)
+		}
+	}
+
+	rankDir := strings.ToUpper(l.g.Attrs["rankdir"])
+	for _, layer := range l.layers {
+		for _, n := range layer {
+			switch rankDir {
+			case "LR":
+				n.x, n.y = across[n], along[n]
+			case "RL":
+				n.x, n.y = maxAcross-across[n], along[n]
+			case "BT":
+				n.x, n.y = along[n], maxAcross-across[n]
+			default:
+				n.x, n.y = along[n], across[n]
+			}
+		}
+	}
+}
+
+// align moves the nodes of a layer towards the mean position of their
+// neighbors while keeping them apart.
+func (l *layout) align(layer []*Node, along map[*Node]float64, neighbors map[*Node][]*Node) {
+	if len(layer) == 0 {
+		return
+	}
+
+	desired := make([]float64, len(layer))
+	for i, n := range layer {
+		desired[i] = along[n]
+		if len(neighbors[n]) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, m := range neighbors[n] {
+			sum += along[m]
+		}
+		desired[i] = sum / float64(len(neighbors[n]))
+	}
+
+	separation := func(i int) float64 {
+		return l.span(layer[i-1])/2 + nodeSep + l.span(layer[i])/2
+	}
+
+	// packing from the left and from the right both keep the nodes apart,
+	// so their mean does too
+	left := make([]float64, len(layer))
+	for i := range layer {
+		left[i] = desired[i]
+		if i > 0 {
+			left[i] = math.Max(left[i], left[i-1]+separation(i))
+		}
+	}
+
+	right := make([]float64, len(layer))
+	for i := len(layer) - 1; i >= 0; i-- {
+		right[i] = desired[i]
+		if i < len(layer)-1 {
+			right[i] = math.Min(right[i], right[i+1]-separation(i+1))
+		}
+	}
+
+	for i, n := range layer {
+		along[n] = (left[i] + right[i]) / 2
+	}
+}
+
+// route computes the points of the edges, clipped to the node outlines.
+func (l *layout) route() {
+	for _, e := range l.g.Edges {
+		if e.From == e.To {
+			continue
+		}
+
+		nodes := e.chain
+		if e.flat {
+			nodes = []*Node{e.From, e.To}
+		} else if e.reversed {
+			nodes = make([]*Node, len(e.chain))
+			for i, n := range e.chain {
+				nodes[len(e.chain)-1-i] = n
+			}
+		}
+
+		points := make([]point, len(nodes))
+		for i, n := range nodes {
+			points[i] = point{n.x, n.y}
+		}
+
+		last := len(points) - 1
+		points[0] = clip(nodes[0], points[1])
+		points[last] = clip(nodes[last], points[last-1])
+
+		e.points = points
+
+		if len(nodes) > 2 {
+			e.label = points[len(points)/2]
+		} else {
+			e.label = point{(points[0].x + points[1].x) / 2, (points[0].y + points[1].y) / 2}
+		}
+	}
+}
+
+// clip returns the point where the line from the center of the node towards
+// the target leaves the node.
+func clip(n *Node, target point) point {
+	dx, dy := target.x-n.x, target.y-n.y
+	if n.virtual || (dx == 0 && dy == 0) {
+		return point{n.x, n.y}
+	}
+
+	a, b := n.width/2, n.height/2
+	var t float64
+
+	switch n.shape {
+	case "ellipse", "oval", "circle", "doublecircle", "point":
+		t = 1 / math.Sqrt(dx*dx/(a*a)+dy*dy/(b*b))
+	case "diamond":
+		t = 1 / (math.Abs(dx)/a + math.Abs(dy)/b)
+	default:
+		t = math.Inf(1)
+		if dx != 0 {
+			t = a / math.Abs(dx)
+		}
+		if dy != 0 {
+			t = math.Min(t, b/math.Abs(dy))
+		}
+	}
+
+	if t > 1 {
+		t = 1
+	}
+
+	return point{n.x + dx*t, n.y + dy*t}
+}
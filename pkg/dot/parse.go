@@ -0,0 +1,483 @@
+package dot
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Graph is a parsed DOT graph. Subgraphs are flattened into the graph, they
+// only scope default attributes.
+type Graph struct {
+	ID       string
+	Directed bool
+	Strict   bool
+	Attrs    map[string]string
+	Nodes    []*Node
+	Edges    []*Edge
+
+	nodes map[string]*Node
+	// sameRank holds the nodes of subgraphs with rank=same
+	sameRank [][]*Node
+}
+
+type Node struct {
+	ID    string
+	Attrs map[string]string
+
+	layoutNode
+}
+
+type Edge struct {
+	From  *Node
+	To    *Node
+	Attrs map[string]string
+
+	layoutEdge
+}
+
+func (g *Graph) node(id string, defaults map[string]string) *Node {
+	if n, ok := g.nodes[id]; ok {
+		return n
+	}
+
+	n := &Node{ID: id, Attrs: copyAttrs(defaults)}
+	g.nodes[id] = n
+	g.Nodes = append(g.Nodes, n)
+
+	return n
+}
+
+func (g *Graph) addEdge(from, to *Node, attrs map[string]string) {
+	if g.Strict {
+		for _, e := range g.Edges {
+			if (e.From == from && e.To == to) || (!g.Directed && e.From == to && e.To == from) {
+				for k, v := range attrs {
+					e.Attrs[k] = v
+				}
+				return
+			}
+		}
+	}
+
+	g.Edges = append(g.Edges, &Edge{From: from, To: to, Attrs: attrs})
+}
+
+// Parse parses a graph in the DOT language.
+func Parse(src string) (*Graph, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	return p.graph()
+}
+
+type tokenKind int
+
+const (
+	tokenID tokenKind = iota
+	tokenPunct
+	tokenEdgeOp
+	tokenEOF
+)
+
+type token struct {
+	kind   tokenKind
+	value  string
+	quoted bool
+	line   int
+}
+
+func tokenize(src string) ([]token, error) {
+	tokens := []token{}
+	line := 1
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case unicode.IsSpace(c):
+			i++
+		case c == '#' && (i == 0 || runes[i-1] == '\n'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				if runes[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			i += 2
+		case c == '-' && i+1 < len(runes) && (runes[i+1] == '>' || runes[i+1] == '-'):
+			tokens = append(tokens, token{kind: tokenEdgeOp, value: string(runes[i : i+2]), line: line})
+			i += 2
+		case strings.ContainsRune("{}[]=;,:", c):
+			tokens = append(tokens, token{kind: tokenPunct, value: string(c), line: line})
+			i++
+		case c == '"':
+			start := line
+			var b strings.Builder
+			i++
+			for i < len(runes) && runes[i] != '"' {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '"' {
+					i++
+				} else if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '\n' {
+					i += 2
+					line++
+					continue
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenID, value: b.String(), quoted: true, line: start})
+		case c == '<':
+			start := i
+			depth := 0
+			for ; i < len(runes); i++ {
+				if runes[i] == '<' {
+					depth++
+				} else if runes[i] == '>' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				} else if runes[i] == '\n' {
+					line++
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("line %d: unterminated HTML string", line)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenID, value: stripTags(string(runes[start+1 : i-1])), quoted: true, line: line})
+		case isIDRune(c) || c == '-' || c == '.':
+			start := i
+			for i < len(runes) && (isIDRune(runes[i]) || runes[i] == '.' || (i == start && runes[i] == '-')) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenID, value: string(runes[start:i]), line: line})
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, line: line}), nil
+}
+
+func isIDRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) || c > unicode.MaxASCII
+}
+
+// stripTags turns an HTML label into plain text.
+func stripTags(s string) string {
+	var b strings.Builder
+
+	inTag := false
+	for _, c := range s {
+		switch {
+		case c == '<':
+			inTag = true
+		case c == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	g      *Graph
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) isPunct(value string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.value == value
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenID && !t.quoted && strings.EqualFold(t.value, keyword)
+}
+
+func (p *parser) expect(value string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.value != value {
+		return p.errorf(t, "expected %q", value)
+	}
+
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	found := t.value
+	if t.kind == tokenEOF {
+		found = "end of input"
+	}
+
+	return fmt.Errorf("line %d: %s, found %q", t.line, fmt.Sprintf(format, args...), found)
+}
+
+func (p *parser) graph() (*Graph, error) {
+	g := &Graph{Attrs: map[string]string{}, nodes: map[string]*Node{}}
+	p.g = g
+
+	if p.isKeyword("strict") {
+		p.next()
+		g.Strict = true
+	}
+
+	switch {
+	case p.isKeyword("digraph"):
+		g.Directed = true
+	case p.isKeyword("graph"):
+	default:
+		return nil, p.errorf(p.peek(), "expected graph or digraph")
+	}
+	p.next()
+
+	if p.peek().kind == tokenID {
+		g.ID = p.next().value
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	if _, err := p.statements(&scope{graph: g.Attrs, node: map[string]string{}, edge: map[string]string{}}); err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "expected end of input")
+	}
+
+	return g, nil
+}
+
+type scope struct {
+	graph map[string]string
+	node  map[string]string
+	edge  map[string]string
+}
+
+// statements parses statements until the closing brace and returns the nodes
+// that were used in them.
+func (p *parser) statements(s *scope) ([]*Node, error) {
+	g := p.g
+	nodes := []*Node{}
+
+	for {
+		for p.isPunct(";") {
+			p.next()
+		}
+
+		if p.isPunct("}") {
+			p.next()
+			return nodes, nil
+		}
+
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return nil, p.errorf(t, "expected }")
+		}
+
+		switch {
+		case p.isKeyword("graph") || p.isKeyword("node") || p.isKeyword("edge"):
+			keyword := strings.ToLower(p.next().value)
+			attrs, err := p.attrList()
+			if err != nil {
+				return nil, err
+			}
+			target := s.graph
+			if keyword == "node" {
+				target = s.node
+			} else if keyword == "edge" {
+				target = s.edge
+			}
+			for k, v := range attrs {
+				target[k] = v
+			}
+			continue
+		case t.kind == tokenID && p.tokens[p.pos+1].kind == tokenPunct && p.tokens[p.pos+1].value == "=":
+			p.pos += 2
+			value := p.next()
+			if value.kind != tokenID {
+				return nil, p.errorf(value, "expected value")
+			}
+			s.graph[t.value] = value.value
+			continue
+		}
+
+		left, err := p.endpoint(s)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, left...)
+
+		if p.peek().kind != tokenEdgeOp {
+			attrs, err := p.attrList()
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range left {
+				for k, v := range attrs {
+					n.Attrs[k] = v
+				}
+			}
+			continue
+		}
+
+		chain := [][]*Node{left}
+		for p.peek().kind == tokenEdgeOp {
+			op := p.next()
+			if g.Directed != (op.value == "->") {
+				return nil, p.errorf(op, "unexpected edge operator")
+			}
+			right, err := p.endpoint(s)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, right...)
+			chain = append(chain, right)
+		}
+
+		attrs, err := p.attrList()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 0; i+1 < len(chain); i++ {
+			for _, from := range chain[i] {
+				for _, to := range chain[i+1] {
+					edgeAttrs := copyAttrs(s.edge)
+					for k, v := range attrs {
+						edgeAttrs[k] = v
+					}
+					g.addEdge(from, to, edgeAttrs)
+				}
+			}
+		}
+	}
+}
+
+// endpoint parses a node or a subgraph.
+func (p *parser) endpoint(s *scope) ([]*Node, error) {
+	if p.isKeyword("subgraph") || p.isPunct("{") {
+		if p.isKeyword("subgraph") {
+			p.next()
+			if p.peek().kind == tokenID {
+				p.next()
+			}
+		}
+		if err := p.expect("{"); err != nil {
+			return nil, err
+		}
+		sub := &scope{graph: map[string]string{}, node: copyAttrs(s.node), edge: copyAttrs(s.edge)}
+		nodes, err := p.statements(sub)
+		if err != nil {
+			return nil, err
+		}
+		if sub.graph["rank"] == "same" {
+			p.g.sameRank = append(p.g.sameRank, nodes)
+		}
+		return nodes, nil
+	}
+
+	t := p.next()
+	if t.kind != tokenID {
+		return nil, p.errorf(t, "expected node")
+	}
+
+	// ports are parsed but not used for the layout
+	for p.isPunct(":") {
+		p.next()
+		if port := p.next(); port.kind != tokenID {
+			return nil, p.errorf(port, "expected port")
+		}
+	}
+
+	return []*Node{p.g.node(t.value, s.node)}, nil
+}
+
+func (p *parser) attrList() (map[string]string, error) {
+	attrs := map[string]string{}
+
+	for p.isPunct("[") {
+		p.next()
+
+		for !p.isPunct("]") {
+			key := p.next()
+			if key.kind != tokenID {
+				return nil, p.errorf(key, "expected attribute")
+			}
+
+			value := "true"
+			if p.isPunct("=") {
+				p.next()
+				t := p.next()
+				if t.kind != tokenID {
+					return nil, p.errorf(t, "expected value")
+				}
+				value = t.value
+			}
+			attrs[key.value] = value
+
+			if p.isPunct(",") || p.isPunct(";") {
+				p.next()
+			}
+		}
+		p.next()
+	}
+
+	return attrs, nil
+}
+
+func copyAttrs(attrs map[string]string) map[string]string {
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+
+	return c
+}
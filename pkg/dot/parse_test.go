@@ -0,0 +1,105 @@
+package dot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summary describes the nodes and edges of a graph with their attributes.
+func summary(g *Graph) string {
+	parts := []string{}
+	for _, n := range g.Nodes {
+		parts = append(parts, fmt.Sprintf("%s %v", n.ID, n.Attrs))
+	}
+	for _, e := range g.Edges {
+		parts = append(parts, fmt.Sprintf("%s->%s %v", e.From.ID, e.To.ID, e.Attrs))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		id       string
+		directed bool
+		strict   bool
+		want     string
+	}{
+		{
+			name:     "edge chain",
+			src:      `digraph G { a -> b -> c }`,
+			id:       "G",
+			directed: true,
+			want:     "a map[] b map[] c map[] a->b map[] b->c map[]",
+		},
+		{
+			name:     "strict graph merges edges",
+			src:      `strict digraph { a -> b; a -> b [label="x"] }`,
+			directed: true,
+			strict:   true,
+			want:     "a map[] b map[] a->b map[label:x]",
+		},
+		{
+			name: "subgraph scopes defaults",
+			src:  `graph { a -- b; subgraph s { node [shape=box]; c } d }`,
+			want: "a map[] b map[] c map[shape:box] d map[] a->b map[]",
+		},
+		{
+			name:     "quoted ids and attributes",
+			src:      `digraph { node [color=red]; a [label="A\nB"]; "x y" -> a }`,
+			directed: true,
+			want:     `a map[color:red label:A\nB] x y map[color:red] x y->a map[]`,
+		},
+		{
+			name:     "html label and comments",
+			src:      "/* c */ digraph { a [label=<<b>bold</b>>] } // c",
+			directed: true,
+			want:     "a map[label:bold]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.src, err)
+			}
+
+			got := []interface{}{g.ID, g.Directed, g.Strict, summary(g)}
+			want := []interface{}{tt.id, tt.directed, tt.strict, tt.want}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.src, got, want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{ a }`, `line 1: expected graph or digraph, found "{"`},
+		{"digraph {\n a -> }", `line 2: expected node, found "}"`},
+		{`digraph { a [label="unterminated }`, "line 1: unterminated string"},
+		{`graph { a -> b }`, `line 1: unexpected edge operator, found "->"`},
+		{`digraph { a -- b }`, `line 1: unexpected edge operator, found "--"`},
+		{`digraph { a } extra`, `line 1: expected end of input, found "extra"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			if err == nil {
+				t.Fatalf("Parse(%q) did not fail, want %q", tt.src, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Parse(%q) error = %q, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
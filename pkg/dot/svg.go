@@ -0,0 +1,363 @@
+package dot
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	arrowLength = 10.0
+	arrowWidth  = 4.0
+	margin      = 8.0
+)
+
+// Render parses a graph in the DOT language and lays it out as an inline SVG
+// element. Strokes and text default to currentColor so the diagram follows
+// the color of the page.
+func Render(src string) (string, error) {
+	g, err := Parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	return newLayout(g).svg(), nil
+}
+
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b *bounds) add(x, y float64) {
+	b.minX = math.Min(b.minX, x)
+	b.minY = math.Min(b.minY, y)
+	b.maxX = math.Max(b.maxX, x)
+	b.maxY = math.Max(b.maxY, y)
+}
+
+func (b *bounds) addText(x, y float64, lines []string, anchor string) {
+	width, height := textSize(lines)
+
+	switch anchor {
+	case "start":
+		b.add(x, y-height/2)
+		b.add(x+width, y+height/2)
+	default:
+		b.add(x-width/2, y-height/2)
+		b.add(x+width/2, y+height/2)
+	}
+}
+
+func (l *layout) svg() string {
+	g := l.g
+	body := &strings.Builder{}
+	b := &bounds{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+
+	for _, e := range g.Edges {
+		l.writeEdge(body, b, e)
+	}
+
+	for _, n := range g.Nodes {
+		writeNode(body, b, n)
+	}
+
+	if len(g.Nodes) == 0 {
+		b.add(0, 0)
+	}
+
+	if label := g.Attrs["label"]; label != "" {
+		lines := labelLines(label, "", g.ID)
+		_, height := textSize(lines)
+		y := b.maxY + margin + height/2
+		if strings.ToLower(g.Attrs["labelloc"]) == "t" {
+			y = b.minY - margin - height/2
+		}
+		x := (b.minX + b.maxX) / 2
+		writeText(body, x, y, lines, "middle", g.Attrs["fontcolor"])
+		b.addText(x, y, lines, "middle")
+	}
+
+	width := b.maxX - b.minX + 2*margin
+	height := b.maxY - b.minY + 2*margin
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" class="graphviz" width="%s" height="%s" viewBox="%s %s %s %s" role="img"`,
+		num(width), num(height), num(b.minX-margin), num(b.minY-margin), num(width), num(height))
+	if g.ID != "" {
+		fmt.Fprintf(out, ` aria-label="%s"`, html.EscapeString(g.ID))
+	}
+	out.WriteString(`><g font-family="ui-sans-serif, system-ui, sans-serif" font-size="14" stroke="currentColor" fill="none">`)
+	out.WriteString(body.String())
+	out.WriteString("</g></svg>")
+
+	return out.String()
+}
+
+func writeNode(w *strings.Builder, b *bounds, n *Node) {
+	style := n.Attrs["style"]
+	if strings.Contains(style, "invis") {
+		return
+	}
+
+	x, y := n.x, n.y
+	rx, ry := n.width/2, n.height/2
+	b.add(x-rx, y-ry)
+	b.add(x+rx, y+ry)
+
+	attrs := shapeAttrs(n.Attrs, style, true)
+
+	switch n.shape {
+	case "plaintext", "plain", "none":
+	case "underline":
+		fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s"%s/>`, num(x-rx), num(y+ry), num(x+rx), num(y+ry), attrs)
+	case "ellipse", "oval", "circle", "point":
+		if n.shape == "point" {
+			attrs = shapeAttrs(n.Attrs, style+",filled", true)
+		}
+		fmt.Fprintf(w, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`, num(x), num(y), num(rx), num(ry), attrs)
+	case "doublecircle":
+		fmt.Fprintf(w, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`, num(x), num(y), num(rx), num(ry), attrs)
+		fmt.Fprintf(w, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s"%s/>`, num(x), num(y), num(rx-4), num(ry-4), shapeAttrs(n.Attrs, style, false))
+	case "diamond":
+		fmt.Fprintf(w, `<polygon points="%s,%s %s,%s %s,%s %s,%s"%s/>`, num(x), num(y-ry), num(x+rx), num(y), num(x), num(y+ry), num(x-rx), num(y), attrs)
+	default:
+		corner := ""
+		if strings.Contains(style, "rounded") || n.shape == "mrecord" {
+			corner = ` rx="6"`
+		}
+		fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s"%s%s/>`, num(x-rx), num(y-ry), num(n.width), num(n.height), corner, attrs)
+		if (n.shape == "record" || n.shape == "mrecord") && len(n.lines) > 1 {
+			// fields are stacked with a separator between them
+			top := y - float64(len(n.lines))*lineHeight/2
+			for i := 1; i < len(n.lines); i++ {
+				lineY := top + float64(i)*lineHeight
+				fmt.Fprintf(w, `<line x1="%s" y1="%s" x2="%s" y2="%s"%s/>`, num(x-rx), num(lineY), num(x+rx), num(lineY), shapeAttrs(n.Attrs, style, false))
+			}
+		}
+	}
+
+	fontColor := n.Attrs["fontcolor"]
+	// text on a filled shape would have the page color otherwise
+	if fontColor == "" && strings.Contains(style, "filled") {
+		fontColor = "black"
+	}
+
+	writeText(w, x, y, n.lines, "middle", fontColor)
+}
+
+func (l *layout) writeEdge(w *strings.Builder, b *bounds, e *Edge) {
+	style := e.Attrs["style"]
+	if strings.Contains(style, "invis") {
+		return
+	}
+
+	attrs := shapeAttrs(e.Attrs, style, false)
+	color := e.Attrs["color"]
+
+	head, tail := l.g.Directed, false
+	switch e.Attrs["dir"] {
+	case "both":
+		head, tail = true, true
+	case "back":
+		head, tail = false, true
+	case "none":
+		head, tail = false, false
+	case "forward":
+		head, tail = true, false
+	}
+	if e.Attrs["arrowhead"] == "none" {
+		head = false
+	}
+	if e.Attrs["arrowtail"] == "none" {
+		tail = false
+	}
+
+	if e.From == e.To {
+		n := e.From
+		x := n.x + n.width/2
+		if n.shape != "box" && n.shape != "rect" && n.shape != "rectangle" && n.shape != "square" {
+			x = n.x + n.width/2*0.87
+		}
+		top, bottom := n.y-n.height/4, n.y+n.height/4
+		fmt.Fprintf(w, `<path d="M%s,%s C%s,%s %s,%s %s,%s"%s/>`,
+			num(x), num(top), num(x+36), num(top-18), num(x+36), num(bottom+18), num(x), num(bottom), attrs)
+		b.add(x+36, top-18)
+		b.add(x+36, bottom+18)
+		if head {
+			writeArrow(w, point{x + 12, bottom + 6}, point{x, bottom}, color)
+		}
+		if label := e.Attrs["label"]; label != "" {
+			lines := labelLines(label, "", l.g.ID)
+			writeText(w, x+40, n.y, lines, "start", e.Attrs["fontcolor"])
+			b.addText(x+40, n.y, lines, "start")
+		}
+		return
+	}
+
+	points := append([]point{}, e.points...)
+	last := len(points) - 1
+
+	// the line stops at the base of the arrow
+	if head {
+		points[last] = shorten(points[last-1], points[last])
+	}
+	if tail {
+		points[0] = shorten(points[1], points[0])
+	}
+
+	fmt.Fprintf(w, `<path d="%s"%s/>`, pathData(points), attrs)
+	for _, p := range e.points {
+		b.add(p.x, p.y)
+	}
+
+	if head {
+		writeArrow(w, e.points[last-1], e.points[last], color)
+	}
+	if tail {
+		writeArrow(w, e.points[1], e.points[0], color)
+	}
+
+	if label := e.Attrs["label"]; label != "" {
+		lines := labelLines(label, "", l.g.ID)
+		x, y := e.label.x+6, e.label.y
+		anchor := "start"
+		if l.horizontal {
+			_, height := textSize(lines)
+			x, y = e.label.x, e.label.y-height/2-2
+			anchor = "middle"
+		}
+		writeText(w, x, y, lines, anchor, e.Attrs["fontcolor"])
+		b.addText(x, y, lines, anchor)
+	}
+}
+
+// pathData joins the points with a Catmull-Rom spline.
+func pathData(points []point) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "M%s,%s", num(points[0].x), num(points[0].y))
+
+	if len(points) == 2 {
+		fmt.Fprintf(&b, " L%s,%s", num(points[1].x), num(points[1].y))
+		return b.String()
+	}
+
+	at := func(i int) point {
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(points) {
+			i = len(points) - 1
+		}
+		return points[i]
+	}
+
+	for i := 0; i+1 < len(points); i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		c1 := point{p1.x + (p2.x-p0.x)/6, p1.y + (p2.y-p0.y)/6}
+		c2 := point{p2.x - (p3.x-p1.x)/6, p2.y - (p3.y-p1.y)/6}
+		fmt.Fprintf(&b, " C%s,%s %s,%s %s,%s", num(c1.x), num(c1.y), num(c2.x), num(c2.y), num(p2.x), num(p2.y))
+	}
+
+	return b.String()
+}
+
+// shorten moves the end of the line from a to b back by the arrow length.
+func shorten(a, b point) point {
+	dx, dy := b.x-a.x, b.y-a.y
+	length := math.Hypot(dx, dy)
+	if length <= arrowLength {
+		return b
+	}
+
+	return point{b.x - dx/length*arrowLength, b.y - dy/length*arrowLength}
+}
+
+// writeArrow draws an arrow head at b pointing away from a.
+func writeArrow(w *strings.Builder, a, b point, color string) {
+	dx, dy := b.x-a.x, b.y-a.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	dx, dy = dx/length, dy/length
+
+	baseX, baseY := b.x-dx*arrowLength, b.y-dy*arrowLength
+	fill := "currentColor"
+	if color != "" {
+		fill = html.EscapeString(color)
+	}
+
+	fmt.Fprintf(w, `<polygon points="%s,%s %s,%s %s,%s" fill="%s" stroke="none"/>`,
+		num(b.x), num(b.y),
+		num(baseX-dy*arrowWidth), num(baseY+dx*arrowWidth),
+		num(baseX+dy*arrowWidth), num(baseY-dx*arrowWidth),
+		fill)
+}
+
+func writeText(w *strings.Builder, x, y float64, lines []string, anchor string, color string) {
+	if len(lines) == 0 {
+		return
+	}
+
+	fill := "currentColor"
+	if color != "" {
+		fill = html.EscapeString(color)
+	}
+
+	fmt.Fprintf(w, `<text x="%s" y="%s" text-anchor="%s" dominant-baseline="central" fill="%s" stroke="none">`, num(x), num(y), anchor, fill)
+	for i, line := range lines {
+		dy := lineHeight
+		if i == 0 {
+			dy = -float64(len(lines)-1) * lineHeight / 2
+		}
+		fmt.Fprintf(w, `<tspan x="%s" dy="%s">%s</tspan>`, num(x), num(dy), html.EscapeString(line))
+	}
+	w.WriteString("</text>")
+}
+
+// shapeAttrs converts the color and style attributes of a node or edge.
+func shapeAttrs(attrs map[string]string, style string, fillable bool) string {
+	var b strings.Builder
+
+	if color := attrs["color"]; color != "" {
+		fmt.Fprintf(&b, ` stroke="%s"`, html.EscapeString(color))
+	}
+
+	if fillable && strings.Contains(style, "filled") {
+		fill := attrs["fillcolor"]
+		if fill == "" {
+			fill = attrs["color"]
+		}
+		if fill == "" {
+			fill = "lightgrey"
+		}
+		fmt.Fprintf(&b, ` fill="%s"`, html.EscapeString(fill))
+	}
+
+	width := 1.0
+	if value, err := strconv.ParseFloat(attrs["penwidth"], 64); err == nil {
+		width = value
+	}
+	if strings.Contains(style, "bold") {
+		width *= 2
+	}
+	if width != 1 {
+		fmt.Fprintf(&b, ` stroke-width="%s"`, num(width))
+	}
+
+	if strings.Contains(style, "dashed") {
+		b.WriteString(` stroke-dasharray="5,3"`)
+	} else if strings.Contains(style, "dotted") {
+		b.WriteString(` stroke-dasharray="1,3"`)
+	}
+
+	return b.String()
+}
+
+func num(f float64) string {
+	// adding zero turns -0 into 0
+	return strconv.FormatFloat(math.Round(f*100)/100+0, 'f', -1, 64)
+}
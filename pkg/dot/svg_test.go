@@ -0,0 +1,52 @@
+package dot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	svg, err := Render(`digraph { a -> b; b -> c; a -> c }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" class="graphviz"`) || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("Render did not return an svg element: %s", svg)
+	}
+
+	for element, want := range map[string]int{"<ellipse": 3, "<path": 3, "<polygon": 3} {
+		if got := strings.Count(svg, element); got != want {
+			t.Errorf("svg has %d %s elements, want %d: %s", got, element, want, svg)
+		}
+	}
+}
+
+func TestRenderEscapesLabels(t *testing.T) {
+	svg, err := Render(`digraph { a [label="<script>&\"x\"", color="red\" onload=\"x"]; a -> b [label="1 < 2"] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unwanted := range []string{"<script>", `" onload="`, "1 < 2"} {
+		if strings.Contains(svg, unwanted) {
+			t.Errorf("svg has unescaped %q: %s", unwanted, svg)
+		}
+	}
+
+	for _, want := range []string{
+		`<tspan x="70.5" dy="0">&lt;script&gt;&amp;&#34;x&#34;</tspan>`,
+		`stroke="red&#34; onload=&#34;x"`,
+		`1 &lt; 2`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not have %q: %s", want, svg)
+		}
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	if _, err := Render(`digraph { a -> }`); err == nil {
+		t.Error("Render of an invalid graph did not fail")
+	}
+}
@@ -0,0 +1,228 @@
+package godoc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Package is a parsed Go package.
+type Package struct {
+	ImportPath string
+	// RelPath is the import path relative to the module.
+	RelPath string
+	Dir     string
+	Files   []string
+	ModTime time.Time
+	Doc     *doc.Package
+
+	fset     *token.FileSet
+	comments []*ast.CommentGroup
+	// imports maps the names that files use for imports to import paths
+	imports map[string]string
+}
+
+// Load parses the packages matched by the patterns, which are directories
+// relative to rootDir. A pattern ending in /... also matches all directories
+// in it, except testdata and directories starting with . or _. Main packages
+// and directories without Go files are skipped.
+func Load(rootDir string, patterns []string) ([]*Package, error) {
+	dirs := []string{}
+	seen := map[string]bool{}
+
+	for _, pattern := range patterns {
+		recursive := strings.HasSuffix(pattern, "/...")
+		dir := filepath.Join(rootDir, strings.TrimSuffix(pattern, "/..."))
+
+		if rel, err := filepath.Rel(rootDir, dir); err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("Package %s is outside of %s", pattern, rootDir)
+		}
+
+		if !recursive {
+			if !seen[dir] {
+				dirs = append(dirs, dir)
+				seen[dir] = true
+			}
+			continue
+		}
+
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+			name := entry.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			if !seen[path] {
+				dirs = append(dirs, path)
+				seen[path] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Could not find packages in %s: %v", pattern, err)
+		}
+	}
+
+	packages := []*Package{}
+
+	for _, dir := range dirs {
+		pkg, err := loadPackage(rootDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		if pkg != nil {
+			packages = append(packages, pkg)
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].ImportPath < packages[j].ImportPath
+	})
+
+	return packages, nil
+}
+
+func loadPackage(rootDir string, dir string) (*Package, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read package %s: %v", dir, err)
+	}
+
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	paths := []string{}
+	name := ""
+	var modTime time.Time
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+		}
+
+		if !strings.HasSuffix(entry.Name(), "_test.go") {
+			name = file.Name.Name
+		}
+
+		if info, err := entry.Info(); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+
+		files = append(files, file)
+		paths = append(paths, path)
+	}
+
+	if name == "" || name == "main" {
+		return nil, nil
+	}
+
+	// files of other packages, such as ignored commands, are left out
+	pkgFiles := []*ast.File{}
+	pkgPaths := []string{}
+	for i, file := range files {
+		if file.Name.Name == name || file.Name.Name == name+"_test" {
+			pkgFiles = append(pkgFiles, file)
+			pkgPaths = append(pkgPaths, paths[i])
+		}
+	}
+
+	modulePath, moduleDir, err := findModule(rootDir, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	relPath, err := filepath.Rel(moduleDir, dir)
+	if err != nil {
+		return nil, err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	importPath := modulePath
+	if relPath != "." {
+		importPath += "/" + relPath
+	} else {
+		relPath = filepath.Base(modulePath)
+	}
+
+	docPkg, err := doc.NewFromFiles(fset, pkgFiles, importPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read documentation of %s: %v", importPath, err)
+	}
+
+	pkg := &Package{
+		ImportPath: importPath,
+		RelPath:    relPath,
+		Dir:        dir,
+		Files:      pkgPaths,
+		ModTime:    modTime,
+		Doc:        docPkg,
+		fset:       fset,
+		imports:    map[string]string{},
+	}
+
+	for _, file := range pkgFiles {
+		pkg.comments = append(pkg.comments, file.Comments...)
+
+		for _, spec := range file.Imports {
+			path := strings.Trim(spec.Path.Value, `"`)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			pkg.imports[name] = path
+		}
+	}
+
+	sort.Slice(pkg.comments, func(i, j int) bool {
+		return pkg.comments[i].Pos() < pkg.comments[j].Pos()
+	})
+
+	return pkg, nil
+}
+
+// findModule returns the module path and directory of the go.mod that the
+// directory belongs to.
+func findModule(rootDir string, dir string) (string, string, error) {
+	for current := dir; ; current = filepath.Dir(current) {
+		file, err := os.Open(filepath.Join(current, "go.mod"))
+		if err == nil {
+			defer file.Close()
+
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				fields := strings.Fields(scanner.Text())
+				if len(fields) == 2 && fields[0] == "module" {
+					return strings.Trim(fields[1], `"`), current, nil
+				}
+			}
+
+			return "", "", fmt.Errorf("No module path in %s", filepath.Join(current, "go.mod"))
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+
+		if current == rootDir || current == filepath.Dir(current) {
+			return "", "", fmt.Errorf("No go.mod found for %s", dir)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package godoc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/doc/comment"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"html"
+	"strings"
+)
+
+// Linker returns the link to the page of a package, or false if the package
+// is not documented on the site.
+type Linker func(importPath string) (string, bool)
+
+type htmlRenderer struct {
+	pkg    *Package
+	linker Linker
+	buf    bytes.Buffer
+	// anchors are the identifiers that have a section on the page
+	anchors map[string]bool
+}
+
+// HTML renders the reference page of the package. Identifiers of the package
+// link to their sections, identifiers of other documented packages link to
+// their pages and other packages link to pkg.go.dev.
+func (p *Package) HTML(linker Linker) string {
+	r := &htmlRenderer{pkg: p, linker: linker, anchors: map[string]bool{}}
+
+	for _, f := range p.Doc.Funcs {
+		r.anchors[f.Name] = true
+	}
+	for _, t := range p.Doc.Types {
+		r.anchors[t.Name] = true
+		for _, f := range t.Funcs {
+			r.anchors[f.Name] = true
+		}
+		for _, m := range t.Methods {
+			r.anchors[t.Name+"."+m.Name] = true
+		}
+	}
+
+	r.render()
+
+	return r.buf.String()
+}
+
+func (r *htmlRenderer) render() {
+	p := r.pkg.Doc
+
+	fmt.Fprintf(&r.buf, "<h1>Package %s</h1>\n", html.EscapeString(p.Name))
+	fmt.Fprintf(&r.buf, "<p class=\"godoc-import\"><code>import &quot;%s&quot;</code></p>\n", html.EscapeString(r.pkg.ImportPath))
+
+	r.doc(p.Doc)
+	r.examples(p.Examples)
+	r.index()
+
+	if len(p.Consts) > 0 {
+		r.buf.WriteString("<h2 id=\"pkg-constants\">Constants</h2>\n")
+		r.values(p.Consts)
+	}
+
+	if len(p.Vars) > 0 {
+		r.buf.WriteString("<h2 id=\"pkg-variables\">Variables</h2>\n")
+		r.values(p.Vars)
+	}
+
+	if len(p.Funcs) > 0 {
+		r.buf.WriteString("<h2 id=\"pkg-functions\">Functions</h2>\n")
+		for _, f := range p.Funcs {
+			r.function(f, "h3", f.Name)
+		}
+	}
+
+	if len(p.Types) > 0 {
+		r.buf.WriteString("<h2 id=\"pkg-types\">Types</h2>\n")
+		for _, t := range p.Types {
+			r.typ(t)
+		}
+	}
+}
+
+func (r *htmlRenderer) index() {
+	p := r.pkg.Doc
+
+	if len(p.Consts)+len(p.Vars)+len(p.Funcs)+len(p.Types) == 0 {
+		return
+	}
+
+	r.buf.WriteString("<h2 id=\"pkg-index\">Index</h2>\n<ul class=\"godoc-index\">\n")
+
+	if len(p.Consts) > 0 {
+		r.buf.WriteString("<li><a href=\"#pkg-constants\">Constants</a></li>\n")
+	}
+	if len(p.Vars) > 0 {
+		r.buf.WriteString("<li><a href=\"#pkg-variables\">Variables</a></li>\n")
+	}
+	for _, f := range p.Funcs {
+		r.indexEntry(f.Name, f.Decl)
+	}
+	for _, t := range p.Types {
+		fmt.Fprintf(&r.buf, "<li><a href=\"#%s\">type %s</a>", t.Name, t.Name)
+		if len(t.Funcs)+len(t.Methods) > 0 {
+			r.buf.WriteString("\n<ul>\n")
+			for _, f := range t.Funcs {
+				r.indexEntry(f.Name, f.Decl)
+			}
+			for _, m := range t.Methods {
+				r.indexEntry(t.Name+"."+m.Name, m.Decl)
+			}
+			r.buf.WriteString("</ul>\n")
+		}
+		r.buf.WriteString("</li>\n")
+	}
+
+	r.buf.WriteString("</ul>\n")
+}
+
+func (r *htmlRenderer) indexEntry(anchor string, decl *ast.FuncDecl) {
+	fmt.Fprintf(&r.buf, "<li><a href=\"#%s\"><code>%s</code></a></li>\n", anchor, html.EscapeString(r.signature(decl)))
+}
+
+func (r *htmlRenderer) values(values []*doc.Value) {
+	for _, v := range values {
+		r.code(v.Decl)
+		r.doc(v.Doc)
+	}
+}
+
+func (r *htmlRenderer) function(f *doc.Func, heading string, anchor string) {
+	title := "func " + f.Name
+	if f.Recv != "" {
+		title = fmt.Sprintf("func (%s) %s", f.Recv, f.Name)
+	}
+
+	fmt.Fprintf(&r.buf, "<%s id=\"%s\">%s</%s>\n", heading, anchor, html.EscapeString(title), heading)
+	r.code(f.Decl)
+	r.doc(f.Doc)
+	r.examples(f.Examples)
+}
+
+func (r *htmlRenderer) typ(t *doc.Type) {
+	fmt.Fprintf(&r.buf, "<h3 id=\"%s\">type %s</h3>\n", t.Name, t.Name)
+	r.code(t.Decl)
+	r.doc(t.Doc)
+	r.examples(t.Examples)
+	r.values(t.Consts)
+	r.values(t.Vars)
+
+	for _, f := range t.Funcs {
+		r.function(f, "h4", f.Name)
+	}
+	for _, m := range t.Methods {
+		r.function(m, "h4", t.Name+"."+m.Name)
+	}
+}
+
+func (r *htmlRenderer) examples(examples []*doc.Example) {
+	for _, ex := range examples {
+		title := "Example"
+		if ex.Suffix != "" {
+			title += " (" + strings.ReplaceAll(ex.Suffix, "_", " ") + ")"
+		}
+
+		r.buf.WriteString("<details class=\"godoc-example\">\n")
+		fmt.Fprintf(&r.buf, "<summary>%s</summary>\n", html.EscapeString(title))
+		r.doc(ex.Doc)
+
+		var code string
+		if ex.Play != nil {
+			code = r.print(ex.Play)
+		} else if block, ok := ex.Code.(*ast.BlockStmt); ok {
+			code = r.print(block)
+			code = strings.TrimSuffix(strings.TrimPrefix(code, "{\n"), "}")
+			code = dedent(code)
+		} else {
+			code = r.print(ex.Code)
+		}
+		r.highlighted(code)
+
+		if ex.Output != "" {
+			r.buf.WriteString("<p>Output:</p>\n")
+			fmt.Fprintf(&r.buf, "<pre class=\"godoc-output\"><code>%s</code></pre>\n", html.EscapeString(ex.Output))
+		}
+
+		r.buf.WriteString("</details>\n")
+	}
+}
+
+func (r *htmlRenderer) doc(text string) {
+	if text == "" {
+		return
+	}
+
+	p := r.pkg.Doc.Printer()
+	p.HeadingLevel = 4
+	p.DocLinkURL = func(link *comment.DocLink) string {
+		anchor := link.Name
+		if link.Recv != "" {
+			anchor = link.Recv + "." + link.Name
+		}
+
+		if link.ImportPath == "" || link.ImportPath == r.pkg.ImportPath {
+			return "#" + anchor
+		}
+
+		return r.packageURL(link.ImportPath, anchor)
+	}
+
+	r.buf.Write(p.HTML(r.pkg.Doc.Parser().Parse(text)))
+}
+
+// packageURL returns the link to an identifier of another package.
+func (r *htmlRenderer) packageURL(importPath string, anchor string) string {
+	url, ok := r.linker(importPath)
+	if !ok {
+		url = "https://pkg.go.dev/" + importPath
+	}
+
+	if anchor != "" {
+		url += "#" + anchor
+	}
+
+	return url
+}
+
+// signature returns the declaration of a function on one line.
+func (r *htmlRenderer) signature(decl *ast.FuncDecl) string {
+	return strings.Join(strings.Fields(r.print(withoutBody(decl))), " ")
+}
+
+func withoutBody(decl *ast.FuncDecl) *ast.FuncDecl {
+	d := *decl
+	d.Body = nil
+	d.Doc = nil
+
+	return &d
+}
+
+// code writes a declaration with its comments, such as the ones of struct
+// fields.
+func (r *htmlRenderer) code(node ast.Node) {
+	var printed interface{} = node
+
+	switch n := node.(type) {
+	case *ast.FuncDecl:
+		printed = withoutBody(n)
+	case *ast.GenDecl:
+		d := *n
+		d.Doc = nil
+		printed = &printer.CommentedNode{Node: &d, Comments: r.pkg.comments}
+	}
+
+	r.highlighted(r.print(printed))
+}
+
+func (r *htmlRenderer) print(node interface{}) string {
+	var buf bytes.Buffer
+
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 4}
+	if err := config.Fprint(&buf, r.pkg.fset, node); err != nil {
+		return err.Error()
+	}
+
+	return buf.String()
+}
+
+// highlighted writes Go code with its identifiers linked.
+func (r *htmlRenderer) highlighted(code string) {
+	src := []byte(code)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, src, func(token.Position, string) {}, scanner.ScanComments)
+
+	r.buf.WriteString("<pre class=\"godoc-code\"><code>")
+
+	last := 0
+	// prev and prevPrev are the tokens before the token, when they are an
+	// identifier followed by a period, so pkg.Name can be linked
+	var prev, prevPrev string
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+
+		start := file.Offset(pos)
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		end := start + len(text)
+		if end > len(src) {
+			end = len(src)
+		}
+
+		r.buf.WriteString(html.EscapeString(string(src[last:start])))
+		last = end
+
+		switch {
+		case tok == token.COMMENT:
+			fmt.Fprintf(&r.buf, "<span class=\"c\">%s</span>", html.EscapeString(text))
+		case tok.IsKeyword():
+			fmt.Fprintf(&r.buf, "<span class=\"k\">%s</span>", text)
+		case tok == token.STRING || tok == token.CHAR:
+			fmt.Fprintf(&r.buf, "<span class=\"s\">%s</span>", html.EscapeString(text))
+		case tok == token.IDENT:
+			href := ""
+			if prev == "." && prevPrev != "" {
+				if importPath, ok := r.pkg.imports[prevPrev]; ok && token.IsExported(text) {
+					href = r.packageURL(importPath, text)
+				}
+			} else if r.anchors[text] {
+				href = "#" + text
+			}
+
+			if href != "" {
+				fmt.Fprintf(&r.buf, "<a href=\"%s\">%s</a>", html.EscapeString(href), text)
+			} else {
+				r.buf.WriteString(text)
+			}
+		default:
+			r.buf.WriteString(html.EscapeString(text))
+		}
+
+		if tok == token.PERIOD {
+			prevPrev, prev = prev, "."
+		} else if tok == token.IDENT {
+			prevPrev, prev = "", text
+		} else {
+			prevPrev, prev = "", ""
+		}
+	}
+
+	r.buf.WriteString(html.EscapeString(string(src[last:])))
+	r.buf.WriteString("</code></pre>\n")
+}
+
+// dedent removes the indent that all lines of the code share.
+func dedent(code string) string {
+	lines := strings.Split(strings.TrimRight(code, "\n"), "\n")
+
+	indent := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first || len(lineIndent) < len(indent) {
+			indent = lineIndent
+			first = false
+		}
+	}
+
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
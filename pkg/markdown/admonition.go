@@ -0,0 +1,178 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	AdmonitionKinds = []string{"note", "tip", "info", "important", "warning", "caution", "danger"}
+
+	alertRegExp = regexp.MustCompile(`^\[!([A-Za-z]+)\]([+-]?)[ \t]*(.*?)\s*$`)
+)
+
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a callout block such as a note or a warning. A collapsible
+// admonition is rendered as a details element, which is open if Open is set.
+type Admonition struct {
+	ast.BaseBlock
+	AdmonitionKind string
+	Title          string
+	Collapsible    bool
+	Open           bool
+
+	container
+}
+
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"AdmonitionKind": n.AdmonitionKind,
+		"Title":          n.Title,
+	}, nil)
+}
+
+func NewAdmonition(kind string, title string, marker string) *Admonition {
+	if title == "" {
+		title = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+
+	return &Admonition{
+		AdmonitionKind: kind,
+		Title:          title,
+		Collapsible:    marker != "",
+		Open:           marker == "+",
+	}
+}
+
+func IsAdmonitionKind(kind string) bool {
+	for _, k := range AdmonitionKinds {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AlertAstTransformer turns GitHub style alerts into admonitions:
+//
+//	> [!NOTE]
+//	> Content
+//
+// A trailing - or + after the marker makes the admonition collapsible and any
+// text after the marker is used as the title.
+type AlertAstTransformer struct{}
+
+func (a AlertAstTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	blockquotes := []*ast.Blockquote{}
+
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if blockquote, ok := n.(*ast.Blockquote); ok && entering {
+			blockquotes = append(blockquotes, blockquote)
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	source := reader.Source()
+
+	for _, blockquote := range blockquotes {
+		paragraph, ok := blockquote.FirstChild().(*ast.Paragraph)
+		if !ok || paragraph.Lines().Len() == 0 {
+			continue
+		}
+
+		firstLine := paragraph.Lines().At(0)
+		match := alertRegExp.FindSubmatch(firstLine.Value(source))
+		if match == nil || !IsAdmonitionKind(strings.ToLower(string(match[1]))) {
+			continue
+		}
+
+		admonition := NewAdmonition(strings.ToLower(string(match[1])), string(match[3]), string(match[2]))
+
+		// Drop the inline nodes of the marker line from the paragraph.
+		for child := paragraph.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			if textNode, ok := child.(*ast.Text); ok && textNode.Segment.Start >= firstLine.Stop {
+				break
+			} else if !ok && !isOnLine(child, firstLine) {
+				break
+			}
+			paragraph.RemoveChild(paragraph, child)
+			child = next
+		}
+
+		paragraph.Lines().SetSliced(1, paragraph.Lines().Len())
+		if paragraph.ChildCount() == 0 {
+			blockquote.RemoveChild(blockquote, paragraph)
+		}
+
+		for child := blockquote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			admonition.AppendChild(admonition, child)
+			child = next
+		}
+
+		blockquote.Parent().ReplaceChild(blockquote.Parent(), blockquote, admonition)
+	}
+}
+
+// isOnLine reports whether all text inside an inline node is on the line.
+func isOnLine(n ast.Node, line text.Segment) bool {
+	onLine := true
+
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if textNode, ok := child.(*ast.Text); ok && entering && textNode.Segment.Start >= line.Stop {
+			onLine = false
+			return ast.WalkStop, nil
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	return onLine
+}
+
+type AdmonitionRenderer struct{}
+
+func (r AdmonitionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, r.renderAdmonition)
+}
+
+func (r AdmonitionRenderer) renderAdmonition(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Admonition)
+
+	tag := "div"
+	titleTag := "p"
+	if n.Collapsible {
+		tag = "details"
+		titleTag = "summary"
+	}
+
+	if !entering {
+		w.WriteString("</" + tag + ">\n")
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString("<" + tag + " class=\"admonition admonition-" + n.AdmonitionKind + "\"")
+	if n.Collapsible && n.Open {
+		w.WriteString(" open")
+	}
+	w.WriteString(">\n")
+	w.WriteString("<" + titleTag + " class=\"admonition-title\">")
+	w.Write(util.EscapeHTML([]byte(n.Title)))
+	w.WriteString("</" + titleTag + ">\n")
+
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/yuin/goldmark/ast"
+)
+
+var (
+	codeBlockRangesRegExp = regexp.MustCompile(`\{([\d,\s-]*)\}`)
+	codeBlockOptionRegExp = regexp.MustCompile(`([\w-]+)(?:=(?:"([^"]*)"|(\S+)))?`)
+)
+
+// CodeBlockOptions are the options that can be set in the info string of a
+// fenced code block, for example:
+//
+//	```go {3-5,9} linenos start=10 title="main.go"
+//	```go file=pkg/config/config.go region=types
+//
+// Sync is the value that selects the tab of the block in groups of tabs.
+type CodeBlockOptions struct {
+	Language       string
+	LineNumbers    bool
+	BaseLineNumber int
+	HighlightLines [][2]int
+	Title          string
+	File           string
+	Lines          string
+	Region         string
+	Sync           string
+	// Unknown are the options that are not one of the above, such as
+	// misspelled ones.
+	Unknown []string
+}
+
+// codeBlockOptionNames are the names of the options of fenced code blocks.
+var codeBlockOptionNames = []string{"linenos", "showLineNumbers", "start", "title", "filename", "file", "lines", "region", "sync"}
+
+func ParseCodeBlockOptions(info string) CodeBlockOptions {
+	options := CodeBlockOptions{
+		BaseLineNumber: 1,
+	}
+
+	info = strings.TrimSpace(info)
+
+	language, rest, _ := strings.Cut(info, " ")
+	if strings.ContainsAny(language, "={") {
+		rest = info
+	} else {
+		options.Language = language
+	}
+
+	if match := codeBlockRangesRegExp.FindStringSubmatch(rest); match != nil {
+		options.HighlightLines = parseLineRanges(match[1])
+		rest = strings.Replace(rest, match[0], "", 1)
+	}
+
+	for _, match := range codeBlockOptionRegExp.FindAllStringSubmatch(rest, -1) {
+		key := match[1]
+		value := match[2] + match[3]
+
+		switch key {
+		case "linenos", "showLineNumbers":
+			options.LineNumbers = value == "" || value == "true"
+		case "start":
+			if start, err := strconv.Atoi(value); err == nil {
+				options.BaseLineNumber = start
+				options.LineNumbers = true
+			}
+		case "title", "filename":
+			options.Title = value
+		case "file":
+			options.File = value
+		case "lines":
+			options.Lines = value
+		case "region":
+			options.Region = value
+		case "sync":
+			options.Sync = value
+		default:
+			options.Unknown = append(options.Unknown, key)
+		}
+	}
+
+	return options
+}
+
+// FormatterOptions converts the options to chroma HTML formatter options.
+// Highlighted lines are relative to the first line of the block, regardless
+// of the starting line number.
+func (o CodeBlockOptions) FormatterOptions() []html.Option {
+	ranges := make([][2]int, 0, len(o.HighlightLines))
+	for _, r := range o.HighlightLines {
+		ranges = append(ranges, [2]int{r[0] + o.BaseLineNumber - 1, r[1] + o.BaseLineNumber - 1})
+	}
+
+	return []html.Option{
+		html.WithClasses(true),
+		html.PreventSurroundingPre(true),
+		html.WithLineNumbers(o.LineNumbers),
+		html.BaseLineNumber(o.BaseLineNumber),
+		html.HighlightLines(ranges),
+	}
+}
+
+func codeBlockInfo(node *ast.FencedCodeBlock, source []byte) string {
+	if node.Info == nil {
+		return ""
+	}
+
+	return string(node.Info.Segment.Value(source))
+}
+
+func parseLineRanges(value string) [][2]int {
+	ranges := [][2]int{}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		startValue, endValue, isRange := strings.Cut(part, "-")
+
+		start, err := strconv.Atoi(strings.TrimSpace(startValue))
+		if err != nil {
+			continue
+		}
+
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(endValue)); err != nil || end < start {
+				continue
+			}
+		}
+
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	return ranges
+}
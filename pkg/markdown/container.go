@@ -0,0 +1,169 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	containerOpenRegExp  = regexp.MustCompile(`^(:{3,})\s*([A-Za-z][\w-]*)([+-]?)(?:\s+(.*?))?\s*$`)
+	containerCloseRegExp = regexp.MustCompile(`^(:{3,})\s*$`)
+)
+
+// fencedContainer is a block opened by a line of three or more colons and
+// closed by a line with at least as many colons.
+type fencedContainer interface {
+	ast.Node
+	containerFenceLength() int
+	setContainerFenceLength(int)
+	containerIsOpen() bool
+	setContainerIsOpen(bool)
+}
+
+type container struct {
+	fenceLength int
+	open        bool
+}
+
+func (c *container) containerFenceLength() int {
+	return c.fenceLength
+}
+
+func (c *container) setContainerFenceLength(length int) {
+	c.fenceLength = length
+}
+
+func (c *container) containerIsOpen() bool {
+	return c.open
+}
+
+func (c *container) setContainerIsOpen(open bool) {
+	c.open = open
+}
+
+// ContainerBlockParser parses fenced containers such as:
+//
+//	:::warning Title
+//	Content
+//	:::
+//
+// A closing fence closes the innermost open container, so containers can be
+// nested without changing the number of colons.
+type ContainerBlockParser struct{}
+
+func (b *ContainerBlockParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (b *ContainerBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || pc.BlockIndent() > 3 {
+		return nil, parser.NoChildren
+	}
+
+	match := containerOpenRegExp.FindSubmatch(line[pos:])
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+
+	name := strings.ToLower(string(match[2]))
+	marker := string(match[3])
+	rest := string(match[4])
+
+	var node fencedContainer
+
+	switch {
+	case IsAdmonitionKind(name):
+		node = NewAdmonition(name, rest, marker)
+	case name == "tabs":
+		node = NewTabs(parseTabsKey(rest), nextTabsID(pc))
+	case name == "tab":
+		node = NewTab(rest)
+	default:
+		return nil, parser.NoChildren
+	}
+
+	node.setContainerFenceLength(len(match[1]))
+	node.setContainerIsOpen(true)
+
+	reader.Advance(segment.Len() - trailingNewlineLength(line))
+
+	return node, parser.HasChildren
+}
+
+func (b *ContainerBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	fenced := node.(fencedContainer)
+
+	if w, pos := util.IndentWidth(line, reader.LineOffset()); w < 4 && !hasOpenContainer(node) {
+		match := containerCloseRegExp.FindSubmatch(line[pos:])
+		if match != nil && len(match[1]) >= fenced.containerFenceLength() {
+			reader.Advance(segment.Len() - trailingNewlineLength(line))
+			return parser.Close
+		}
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (b *ContainerBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	node.(fencedContainer).setContainerIsOpen(false)
+
+	if tabs, ok := node.(*Tabs); ok {
+		tabs.wrapCodeBlocks(reader.Source())
+	}
+}
+
+func (b *ContainerBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *ContainerBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// hasOpenContainer reports whether a container nested in the node is still
+// open, in which case a closing fence belongs to it.
+func hasOpenContainer(node ast.Node) bool {
+	for child := node.LastChild(); child != nil; child = child.LastChild() {
+		if fenced, ok := child.(fencedContainer); ok && fenced.containerIsOpen() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trailingNewlineLength(line []byte) int {
+	if bytes.HasSuffix(line, []byte("\r\n")) {
+		return 2
+	} else if bytes.HasSuffix(line, []byte("\n")) {
+		return 1
+	}
+
+	return 0
+}
+
+// Containers is an extension that adds fenced containers for admonitions and
+// tabs, as well as GitHub style alerts.
+type Containers struct{}
+
+func (e *Containers) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&ContainerBlockParser{}, 50)),
+		parser.WithASTTransformers(util.Prioritized(&AlertAstTransformer{}, 700)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&AdmonitionRenderer{}, 100),
+		util.Prioritized(&TabsRenderer{}, 100),
+	))
+}
@@ -0,0 +1,107 @@
+package markdown
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/yuin/goldmark/parser"
+)
+
+var (
+	rootDirKey      = parser.NewContextKey()
+	linksKey        = parser.NewContextKey()
+	dependenciesKey = parser.NewContextKey()
+	warningsKey     = parser.NewContextKey()
+	errorsKey       = parser.NewContextKey()
+)
+
+// Warning is a problem with a page that does not stop it from being built.
+// Line is relative to the start of the markdown passed to the converter.
+type Warning struct {
+	Line    int
+	Message string
+}
+
+// NewContext creates a parser context for converting a single page. Files
+// referenced from the page are resolved relative to rootDir.
+func NewContext(rootDir string) parser.Context {
+	pc := parser.NewContext()
+	pc.Set(rootDirKey, rootDir)
+
+	return pc
+}
+
+// SetLinks sets the site-level code block links, which are used for any link
+// that is not declared in the code block itself.
+func SetLinks(pc parser.Context, links map[string]string) {
+	pc.Set(linksKey, links)
+}
+
+// Dependencies returns the files, other than the page itself, that were read
+// while converting the page.
+func Dependencies(pc parser.Context) []string {
+	if dependencies, ok := pc.Get(dependenciesKey).([]string); ok {
+		return dependencies
+	}
+
+	return []string{}
+}
+
+// Warnings returns the warnings that occurred while converting the page.
+func Warnings(pc parser.Context) []Warning {
+	if warnings, ok := pc.Get(warningsKey).([]Warning); ok {
+		return warnings
+	}
+
+	return []Warning{}
+}
+
+// Err returns the errors that occurred while converting the page.
+func Err(pc parser.Context) error {
+	if result, ok := pc.Get(errorsKey).(*multierror.Error); ok {
+		return result.ErrorOrNil()
+	}
+
+	return nil
+}
+
+func rootDir(pc parser.Context) string {
+	if dir, ok := pc.Get(rootDirKey).(string); ok {
+		return dir
+	}
+
+	return "."
+}
+
+func siteLinks(pc parser.Context) map[string]string {
+	if links, ok := pc.Get(linksKey).(map[string]string); ok {
+		return links
+	}
+
+	return map[string]string{}
+}
+
+func addDependency(pc parser.Context, path string) {
+	dependencies := Dependencies(pc)
+
+	for _, dependency := range dependencies {
+		if dependency == path {
+			return
+		}
+	}
+
+	pc.Set(dependenciesKey, append(dependencies, path))
+}
+
+func addWarning(pc parser.Context, line int, format string, args ...interface{}) {
+	pc.Set(warningsKey, append(Warnings(pc), Warning{
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	}))
+}
+
+func addError(pc parser.Context, format string, args ...interface{}) {
+	result, _ := pc.Get(errorsKey).(*multierror.Error)
+
+	pc.Set(errorsKey, multierror.Append(result, fmt.Errorf(format, args...)))
+}
@@ -0,0 +1,123 @@
+package markdown
+
+import (
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/dot"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	KindDiagram = ast.NewNodeKind("Diagram")
+
+	mermaidKey = parser.NewContextKey()
+)
+
+// Diagram is a fenced code block with a diagram language. Mermaid diagrams
+// are drawn in the browser, DOT graphs are drawn to SVG while building.
+type Diagram struct {
+	ast.BaseBlock
+	Language string
+	Source   string
+	SVG      string
+}
+
+func (n *Diagram) Kind() ast.NodeKind {
+	return KindDiagram
+}
+
+func (n *Diagram) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Language": n.Language}, nil)
+}
+
+// UsesMermaid reports whether the converted page has a mermaid diagram, in
+// which case the page needs the mermaid script.
+func UsesMermaid(pc parser.Context) bool {
+	uses, _ := pc.Get(mermaidKey).(bool)
+
+	return uses
+}
+
+// DiagramAstTransformer replaces ```mermaid and ```dot code blocks with
+// diagrams. A DOT graph that cannot be parsed is left as a code block and
+// reported as a warning.
+type DiagramAstTransformer struct{}
+
+func (a DiagramAstTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	codeBlocks := []*ast.FencedCodeBlock{}
+
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if codeBlock, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			codeBlocks = append(codeBlocks, codeBlock)
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	source := reader.Source()
+
+	for _, codeBlock := range codeBlocks {
+		language := strings.ToLower(ParseCodeBlockOptions(codeBlockInfo(codeBlock, source)).Language)
+		if language != "mermaid" && language != "dot" && language != "graphviz" {
+			continue
+		}
+
+		var content string
+		if snippet, ok := codeBlock.AttributeString("snippet"); ok {
+			content = snippet.(string)
+		} else {
+			for i := 0; i < codeBlock.Lines().Len(); i++ {
+				line := codeBlock.Lines().At(i)
+				content += string(line.Value(source))
+			}
+		}
+
+		diagram := &Diagram{Language: language, Source: content}
+
+		if language == "mermaid" {
+			pc.Set(mermaidKey, true)
+		} else {
+			diagram.Language = "dot"
+
+			svg, err := dot.Render(content)
+			if err != nil {
+				addWarning(pc, lineNumber(source, codeBlock), "invalid dot graph: %s", err)
+				continue
+			}
+			diagram.SVG = svg
+		}
+
+		codeBlock.Parent().ReplaceChild(codeBlock.Parent(), codeBlock, diagram)
+	}
+}
+
+type DiagramRenderer struct{}
+
+func (r DiagramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindDiagram, r.renderDiagram)
+}
+
+func (r DiagramRenderer) renderDiagram(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*Diagram)
+
+	if n.Language == "mermaid" {
+		// mermaid reads the text content, so the source is escaped
+		w.WriteString("<pre class=\"mermaid\">")
+		w.Write(util.EscapeHTML([]byte(n.Source)))
+		w.WriteString("</pre>\n")
+	} else {
+		w.WriteString("<div class=\"diagram diagram-dot\">")
+		w.WriteString(n.SVG)
+		w.WriteString("</div>\n")
+	}
+
+	return ast.WalkSkipChildren, nil
+}
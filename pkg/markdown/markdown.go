@@ -3,24 +3,34 @@ package markdown
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/formatters/html"
 	"github.com/alecthomas/chroma/lexers"
 	"github.com/alecthomas/chroma/styles"
+	"github.com/lukeshay/gocden/pkg/config"
 	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
 
+var codeBlockLinkRegExp = regexp.MustCompile(`\$\$([A-Za-z_][\w.]*)`)
+
 type CodeBlockLinksAstTransformer struct{}
 
 func (a CodeBlockLinksAstTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	links := siteLinks(pc)
+
 	walker := func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
@@ -43,6 +53,28 @@ func (a CodeBlockLinksAstTransformer) Transform(node *ast.Document, reader text.
 			n.SetAttribute([]byte("link:"+keyValue[0]), keyValue[1])
 		}
 		n.Lines().SetSliced(defCount, n.Lines().Len())
+
+		content := ""
+		if snippet, ok := n.AttributeString("snippet"); ok {
+			content = snippet.(string)
+		} else {
+			for i := 0; i < n.Lines().Len(); i++ {
+				content += string(reader.Value(n.Lines().At(i)))
+			}
+		}
+
+		for _, match := range codeBlockLinkRegExp.FindAllStringSubmatch(content, -1) {
+			name := match[1]
+			if _, ok := n.AttributeString("link:" + name); ok {
+				continue
+			}
+			if dest, ok := links[name]; ok {
+				n.SetAttribute([]byte("link:"+name), dest)
+				continue
+			}
+			addWarning(pc, lineNumber(reader.Source(), n), "unresolved code block link $$%s", name)
+		}
+
 		return ast.WalkContinue, nil
 	}
 	ast.Walk(node, walker)
@@ -65,48 +97,221 @@ func (r CodeBlockLinksRenderer) renderCustomCodeBlockLinks(w util.BufWriter, sou
 		line := codeBlock.Lines().At(i)
 		content += string(line.Value(source))
 	}
-	lexer := lexers.Get(string(codeBlock.Language(source)))
+
+	options := ParseCodeBlockOptions(codeBlockInfo(codeBlock, source))
+	if _, ok := node.AttributeString("tab"); ok {
+		options.Title = ""
+	}
+
+	lexer := lexers.Get(options.Language)
+
+	if snippet, ok := node.AttributeString("snippet"); ok {
+		content = snippet.(string)
+
+		if lexer == nil {
+			file, _ := node.AttributeString("snippet:file")
+			lexer = lexers.Match(file.(string))
+		}
+	}
+
 	if lexer == nil {
-		w.WriteString("<pre class=\"codeblock\"><code>")
-		w.WriteString(content)
-		w.WriteString("</code></pre>")
-		return ast.WalkContinue, nil
+		lexer = lexers.Fallback
 	}
 	lexer = chroma.Coalesce(lexer)
 
-	formatter := html.New(html.WithClasses(true), html.PreventSurroundingPre(true))
+	formatter := html.New(options.FormatterOptions()...)
 
-	iterator, err := lexer.Tokenise(nil, content)
-	if err != nil {
-		return ast.WalkStop, err
-	}
-	buf := new(bytes.Buffer)
-	// random style
-	formatter.Format(buf, styles.GitHub, iterator)
+	attributes := append([]ast.Attribute{}, node.Attributes()...)
+	// longer names first so that $$name does not replace part of $$nameLonger
+	sort.SliceStable(attributes, func(i, j int) bool {
+		return len(attributes[i].Name) > len(attributes[j].Name)
+	})
 
-	html := buf.String()
-	for _, attribute := range node.Attributes() {
+	// Links are replaced with placeholders before highlighting because the
+	// lexer may split $$ and the name into separate tokens.
+	links := map[string]string{}
+	for _, attribute := range attributes {
 		attributeName := string(attribute.Name)
 		if !strings.HasPrefix(attributeName, "link:") {
 			continue
 		}
 		target := strings.Replace(attributeName, "link:", "", 1)
 		dest := attribute.Value.(string)
-		html = strings.ReplaceAll(html, "$$"+target, fmt.Sprintf("<a href=\"%s\">%s</a>", dest, target))
+		if !strings.Contains(content, "$$"+target) {
+			continue
+		}
+		placeholder := fmt.Sprintf("gocdenlink%04dx", len(links))
+		content = strings.ReplaceAll(content, "$$"+target, placeholder)
+		links[placeholder] = fmt.Sprintf("<a href=\"%s\">%s</a>", dest, target)
 	}
 
-	w.WriteString("<pre class=\"codeblock\"><code>")
+	iterator, err := lexer.Tokenise(nil, content)
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	buf := new(bytes.Buffer)
+	// classes are emitted, so the style only matters for the stylesheet
+	if err := formatter.Format(buf, styles.Fallback, iterator); err != nil {
+		return ast.WalkStop, err
+	}
+
+	html := buf.String()
+	for placeholder, link := range links {
+		html = strings.ReplaceAll(html, placeholder, link)
+	}
+
+	if options.Title != "" {
+		w.WriteString("<figure class=\"codeblock-figure\"><figcaption class=\"codeblock-title\">")
+		w.Write(util.EscapeHTML([]byte(options.Title)))
+		w.WriteString("</figcaption>")
+	}
+
+	w.WriteString("<pre class=\"codeblock chroma\"><code>")
 	w.WriteString(html)
 	w.WriteString("</code></pre>")
 
+	if options.Title != "" {
+		w.WriteString("</figure>")
+	}
+
 	return ast.WalkContinue, nil
 }
 
-func Create() goldmark.Markdown {
-	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+// lineNumber returns the 1-based line of the node in the source, or 0 if it
+// is not known.
+func lineNumber(source []byte, n ast.Node) int {
+	offset := -1
+	if codeBlock, ok := n.(*ast.FencedCodeBlock); ok && codeBlock.Info != nil {
+		offset = codeBlock.Info.Segment.Start
+	} else if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		offset = n.Lines().At(0).Start
+	}
+
+	if offset < 0 {
+		return 0
+	}
+
+	return lineOfOffset(source, offset)
+}
+
+// lineOfOffset returns the 1-based line of a byte offset in the source.
+func lineOfOffset(source []byte, offset int) int {
+	return bytes.Count(source[:offset], []byte("\n")) + 1
+}
+
+// WriteCSS writes the stylesheet for highlighted code blocks. The light style
+// applies when the page does not have the dark class set and the dark style
+// applies when it does, so neither style leaks into the other.
+func WriteCSS(w io.Writer, light *chroma.Style, dark *chroma.Style) error {
+	for _, style := range []struct {
+		style *chroma.Style
+		scope string
+	}{{light, "html:not(.dark)"}, {dark, ".dark"}} {
+		css, err := styleCSS(style.style)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, scopeCSS(css, style.scope)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// styleCSS returns the chroma stylesheet of the style without its background
+// rule, which is for the body of standalone pages and would style any element
+// of the page with the bg class.
+func styleCSS(style *chroma.Style) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := html.New(html.WithClasses(true)).WriteCSS(buf, style); err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "/* Background */") {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// scopeCSS prefixes the selectors of the rules of a chroma stylesheet, which
+// has a rule per line, with the scope selector. chroma's ClassPrefix renames
+// the classes of the tokens instead, which the highlighted code would then
+// have to use.
+func scopeCSS(css string, scope string) string {
+	lines := strings.Split(css, "\n")
+
+	for i, line := range lines {
+		comment := ""
+		if strings.HasPrefix(line, "/*") {
+			if end := strings.Index(line, "*/"); end >= 0 {
+				comment, line = line[:end+len("*/")]+" ", line[end+len("*/"):]
+			}
+		}
+
+		selectors, declarations, ok := strings.Cut(line, "{")
+		if !ok {
+			continue
+		}
+
+		scoped := strings.Split(selectors, ",")
+		for j, selector := range scoped {
+			scoped[j] = scope + " " + strings.TrimSpace(selector)
+		}
+
+		lines[i] = comment + strings.Join(scoped, ", ") + " {" + declarations
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func Create(conf *config.Config) goldmark.Markdown {
+	extensions := []goldmark.Extender{extension.GFM, &Containers{}}
+
+	if conf.Markdown.Footnote {
+		extensions = append(extensions, extension.Footnote)
+	}
+	if conf.Markdown.DefinitionList {
+		extensions = append(extensions, extension.DefinitionList)
+	}
+	if conf.Markdown.Typographer {
+		extensions = append(extensions, extension.Typographer)
+	}
+	if conf.Markdown.Emoji {
+		extensions = append(extensions, emoji.Emoji)
+	}
+	if conf.Markdown.Math {
+		extensions = append(extensions, &MathExtension{})
+	}
+
+	rendererOptions := []renderer.Option{}
+
+	if conf.Markdown.Unsafe {
+		rendererOptions = append(rendererOptions, goldmarkhtml.WithUnsafe())
+	}
+	if conf.Markdown.HardWraps {
+		rendererOptions = append(rendererOptions, goldmarkhtml.WithHardWraps())
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(extensions...), goldmark.WithRendererOptions(rendererOptions...))
 
-	md.Parser().AddOptions(parser.WithASTTransformers(util.Prioritized(&CodeBlockLinksAstTransformer{}, 500)), parser.WithAutoHeadingID())
-	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&CodeBlockLinksRenderer{}, 100)))
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(&SnippetAstTransformer{}, 400),
+			util.Prioritized(&DiagramAstTransformer{}, 450),
+			util.Prioritized(&CodeBlockLinksAstTransformer{}, 500),
+		),
+		parser.WithAutoHeadingID(),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&CodeBlockLinksRenderer{}, 100),
+		util.Prioritized(&DiagramRenderer{}, 100),
+	))
 
 	return md
 }
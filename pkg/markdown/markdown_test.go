@@ -0,0 +1,69 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/styles"
+	"github.com/lukeshay/gocden/pkg/config"
+)
+
+func TestWriteCSSScopesStyles(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSS(&buf, styles.Get("github"), styles.Get("dracula")); err != nil {
+		t.Fatal(err)
+	}
+
+	light, dark := 0, 0
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		_, rule, _ := strings.Cut(line, "*/ ")
+		selectors, _, ok := strings.Cut(rule, "{")
+		if !ok {
+			t.Errorf("line is not a rule: %q", line)
+			continue
+		}
+
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+
+			switch {
+			case strings.HasPrefix(selector, "html:not(.dark) .chroma"):
+				light++
+			case strings.HasPrefix(selector, ".dark .chroma"):
+				dark++
+			default:
+				t.Errorf("selector %q is not scoped to the code of the light or dark theme", selector)
+			}
+		}
+	}
+
+	if light == 0 || dark == 0 {
+		t.Errorf("expected light and dark rules, got %d light and %d dark", light, dark)
+	}
+}
+
+func TestMathLeavesPrices(t *testing.T) {
+	conf := config.Default()
+	conf.Markdown.Math = true
+
+	tests := []struct {
+		source string
+		math   bool
+	}{
+		{"It costs $5 and $10.", false},
+		{`It costs \$5.`, false},
+		{"Euler: $e^{i\\pi}$.", true},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Create(conf).Convert([]byte(tt.source), &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := strings.Contains(buf.String(), "<math"); got != tt.math {
+			t.Errorf("%q rendered math = %v, want %v: %s", tt.source, got, tt.math, buf.String())
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/mathml"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	KindMath      = ast.NewNodeKind("Math")
+	KindMathBlock = ast.NewNodeKind("MathBlock")
+)
+
+// Math is an inline formula written as $...$ or $$...$$. The formula is
+// converted to MathML while parsing, so no script is needed on the page.
+type Math struct {
+	ast.BaseInline
+	TeX     string
+	Display bool
+	MathML  string
+	Err     error
+}
+
+func (n *Math) Kind() ast.NodeKind {
+	return KindMath
+}
+
+func (n *Math) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"TeX": n.TeX}, nil)
+}
+
+func NewMath(tex string, display bool) *Math {
+	n := &Math{
+		TeX:     tex,
+		Display: display,
+	}
+	n.MathML, n.Err = mathml.Convert(tex, display)
+
+	return n
+}
+
+// MathBlock is a formula displayed on its own, written as:
+//
+//	$$
+//	E = mc^2
+//	$$
+type MathBlock struct {
+	ast.BaseBlock
+	MathML string
+	Err    error
+
+	closed bool
+}
+
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// TeX returns the formula of the block.
+func (n *MathBlock) TeX(source []byte) string {
+	var b strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		b.Write(line.Value(source))
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// MathInlineParser parses inline formulas. Like pandoc, the opening $ must be
+// followed by a non-space character and the closing $ must be preceded by a
+// non-space character and not followed by a digit, so prices such as $5 and
+// $10 are left alone. A literal dollar sign can be escaped as \$.
+type MathInlineParser struct{}
+
+func (s *MathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (s *MathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+
+	delimiter := []byte("$")
+	if bytes.HasPrefix(line, []byte("$$")) {
+		delimiter = []byte("$$")
+	}
+
+	start := len(delimiter)
+	if start >= len(line) || util.IsSpace(line[start]) {
+		return nil
+	}
+
+	end := -1
+	for i := start + 1; i+len(delimiter) <= len(line); i++ {
+		if line[i-1] == '\\' {
+			continue
+		}
+		if !bytes.HasPrefix(line[i:], delimiter) {
+			continue
+		}
+		// a $ that cannot close the formula means this is not a formula
+		if util.IsSpace(line[i-1]) {
+			break
+		}
+		if next := i + len(delimiter); len(delimiter) == 1 && next < len(line) && (line[next] >= '0' && line[next] <= '9' || line[next] == '$') {
+			break
+		}
+		end = i
+		break
+	}
+
+	if end < 0 {
+		return nil
+	}
+
+	n := NewMath(string(line[start:end]), len(delimiter) == 2)
+	if n.Err != nil {
+		addWarning(pc, lineOfOffset(block.Source(), segment.Start), "invalid math: %s", n.Err)
+	}
+
+	block.Advance(end + len(delimiter))
+
+	return n
+}
+
+// MathBlockParser parses display formulas fenced by $$. The fences may be on
+// the same line as the formula.
+type MathBlockParser struct{}
+
+func (b *MathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (b *MathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || pc.BlockIndent() > 3 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+
+	rest := bytes.TrimRight(line[pos+2:], " \t\r\n")
+	if i := bytes.Index(rest, []byte("$$")); i >= 0 {
+		// a single line block must end with the closing fence
+		if i != len(rest)-2 {
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(segment.Start+pos+2, segment.Start+pos+2+i))
+		node.closed = true
+	} else if len(bytes.TrimSpace(rest)) > 0 {
+		node.Lines().Append(text.NewSegment(segment.Start+pos+2, segment.Stop))
+	}
+
+	reader.Advance(segment.Len() - trailingNewlineLength(line))
+
+	return node, parser.NoChildren
+}
+
+func (b *MathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*MathBlock)
+	if n.closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	if line == nil {
+		return parser.Close
+	}
+
+	trimmed := bytes.TrimRight(line, " \t\r\n")
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		n.Lines().Append(text.NewSegment(segment.Start, segment.Start+len(trimmed)-2))
+		reader.Advance(segment.Len() - trailingNewlineLength(line))
+		n.closed = true
+		return parser.Close
+	}
+
+	n.Lines().Append(segment)
+	reader.Advance(segment.Len() - trailingNewlineLength(line))
+
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *MathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	n := node.(*MathBlock)
+
+	n.MathML, n.Err = mathml.Convert(n.TeX(reader.Source()), true)
+	if n.Err != nil {
+		addWarning(pc, lineNumber(reader.Source(), n), "invalid math: %s", n.Err)
+	} else if !n.closed {
+		addWarning(pc, lineNumber(reader.Source(), n), "math block is not closed with $$")
+	}
+}
+
+func (b *MathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *MathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type MathRenderer struct{}
+
+func (r MathRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMath, r.renderMath)
+	reg.Register(KindMathBlock, r.renderMathBlock)
+}
+
+func (r MathRenderer) renderMath(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*Math)
+	if n.Err != nil {
+		writeMathError(w, n.TeX, n.Err)
+		return ast.WalkSkipChildren, nil
+	}
+
+	w.WriteString(n.MathML)
+
+	return ast.WalkSkipChildren, nil
+}
+
+func (r MathRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	n := node.(*MathBlock)
+
+	w.WriteString("<div class=\"math-display\">")
+	if n.Err != nil {
+		writeMathError(w, n.TeX(source), n.Err)
+	} else {
+		w.WriteString(n.MathML)
+	}
+	w.WriteString("</div>\n")
+
+	return ast.WalkSkipChildren, nil
+}
+
+// writeMathError writes the source of a formula that could not be converted.
+func writeMathError(w util.BufWriter, tex string, err error) {
+	w.WriteString("<code class=\"math-error\" title=\"")
+	w.Write(util.EscapeHTML([]byte(err.Error())))
+	w.WriteString("\">")
+	w.Write(util.EscapeHTML([]byte(tex)))
+	w.WriteString("</code>")
+}
+
+// MathExtension is an extension that converts $...$ and $$...$$ formulas to MathML.
+type MathExtension struct{}
+
+func (e *MathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&MathBlockParser{}, 150)),
+		parser.WithInlineParsers(util.Prioritized(&MathInlineParser{}, 150)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&MathRenderer{}, 100)))
+}
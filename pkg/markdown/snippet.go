@@ -0,0 +1,186 @@
+package markdown
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+var regionMarkerRegExp = regexp.MustCompile(`\b(end)?region:[\w-]+\b`)
+
+// SnippetAstTransformer replaces the contents of fenced code blocks that have
+// a file option with the contents of that file, for example:
+//
+//	```go file=pkg/config/config.go lines=10-20
+//	```go file=pkg/config/config.go region=types
+//
+// A region is delimited by region:name and endregion:name marker comments.
+// Options of code blocks that are not known, such as misspelled ones, are
+// warned about.
+type SnippetAstTransformer struct{}
+
+func (a SnippetAstTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != ast.KindFencedCodeBlock {
+			return ast.WalkContinue, nil
+		}
+
+		options := ParseCodeBlockOptions(codeBlockInfo(n.(*ast.FencedCodeBlock), reader.Source()))
+
+		for _, name := range options.Unknown {
+			addWarning(pc, lineNumber(reader.Source(), n), "unknown code block option %q, expected one of: %s", name, strings.Join(codeBlockOptionNames, ", "))
+		}
+
+		if options.File == "" {
+			return ast.WalkContinue, nil
+		}
+
+		snippet, err := ReadSnippet(rootDir(pc), options)
+		if err != nil {
+			addError(pc, "Could not include snippet %s: %v", options.File, err)
+			return ast.WalkContinue, nil
+		}
+
+		addDependency(pc, filepath.Join(rootDir(pc), options.File))
+
+		n.SetAttributeString("snippet", snippet)
+		n.SetAttributeString("snippet:file", options.File)
+
+		return ast.WalkContinue, nil
+	})
+}
+
+// ReadSnippet reads the part of the file selected by the options. The file
+// must be inside rootDir.
+func ReadSnippet(rootDir string, options CodeBlockOptions) (string, error) {
+	path := filepath.Join(rootDir, options.File)
+
+	if rel, err := filepath.Rel(rootDir, path); err != nil || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("file is outside of %s", rootDir)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if options.Region != "" {
+		if lines, err = selectRegion(lines, options.Region); err != nil {
+			return "", err
+		}
+	}
+
+	if options.Lines != "" {
+		if lines, err = selectLines(lines, options.Lines); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.Join(dedent(lines), ""), nil
+}
+
+func selectRegion(lines []string, region string) ([]string, error) {
+	start := regexp.MustCompile(`\bregion:` + regexp.QuoteMeta(region) + `\b`)
+	end := regexp.MustCompile(`\bendregion:` + regexp.QuoteMeta(region) + `\b`)
+
+	selected := []string{}
+	inRegion := false
+	found := false
+
+	for _, line := range lines {
+		if !inRegion && start.MatchString(line) && !end.MatchString(line) {
+			inRegion = true
+			found = true
+			continue
+		}
+
+		if inRegion && end.MatchString(line) {
+			inRegion = false
+			continue
+		}
+
+		if inRegion && !regionMarkerRegExp.MatchString(line) {
+			selected = append(selected, line)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("region %q not found", region)
+	}
+
+	return selected, nil
+}
+
+func selectLines(lines []string, value string) ([]string, error) {
+	startValue, endValue, isRange := strings.Cut(value, "-")
+
+	start, err := strconv.Atoi(startValue)
+	if err != nil || start < 1 {
+		return nil, fmt.Errorf("invalid lines %q", value)
+	}
+
+	end := start
+	if isRange {
+		if endValue == "" {
+			end = len(lines)
+		} else if end, err = strconv.Atoi(endValue); err != nil || end < start {
+			return nil, fmt.Errorf("invalid lines %q", value)
+		}
+	}
+
+	if start > len(lines) {
+		return nil, fmt.Errorf("lines %q are out of range, the file has %d lines", value, len(lines))
+	}
+
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	return lines[start-1 : end], nil
+}
+
+func dedent(lines []string) []string {
+	indent := ""
+	first := true
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+		if first {
+			indent = lineIndent
+			first = false
+			continue
+		}
+
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	if indent == "" {
+		return lines
+	}
+
+	dedented := make([]string, 0, len(lines))
+	for _, line := range lines {
+		dedented = append(dedented, strings.TrimPrefix(line, indent))
+	}
+
+	return dedented
+}
@@ -0,0 +1,222 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+var (
+	KindTabs = ast.NewNodeKind("Tabs")
+	KindTab  = ast.NewNodeKind("Tab")
+
+	tabsIDKey = parser.NewContextKey()
+)
+
+// Tabs is a group of tabs, written as:
+//
+//	:::tabs key=lang
+//	```go
+//	```
+//	```powershell
+//	```
+//	:::
+//
+// Fenced code blocks directly inside the group become tabs labeled with their
+// title or language. Other content can be put in tabs with :::tab Label.
+// Groups with the same key select the same tab and remember the selection.
+// Tabs of code blocks are the same tab when they have the same language, and
+// a tab with a title is the same tab as the ones of its sync option, such as
+// a tab of main.go with sync=go and a tab of go code.
+type Tabs struct {
+	ast.BaseBlock
+	Key string
+	ID  int
+
+	container
+}
+
+func (n *Tabs) Kind() ast.NodeKind {
+	return KindTabs
+}
+
+func (n *Tabs) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Key": n.Key}, nil)
+}
+
+func NewTabs(key string, id int) *Tabs {
+	return &Tabs{
+		Key: key,
+		ID:  id,
+	}
+}
+
+// Tab is a single tab in a group of tabs. Value selects the tab in the groups
+// with the same key, which is the label, or the sync option or language of
+// the code block of the tab.
+type Tab struct {
+	ast.BaseBlock
+	Label string
+	Value string
+
+	container
+}
+
+func (n *Tab) Kind() ast.NodeKind {
+	return KindTab
+}
+
+func (n *Tab) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Label": n.Label, "Value": n.Value}, nil)
+}
+
+func NewTab(label string) *Tab {
+	label = strings.TrimSpace(label)
+
+	return &Tab{
+		Label: label,
+		Value: label,
+	}
+}
+
+// wrapCodeBlocks puts each fenced code block that is a direct child of the
+// group into its own tab.
+func (n *Tabs) wrapCodeBlocks(source []byte) {
+	for child := n.FirstChild(); child != nil; {
+		next := child.NextSibling()
+
+		if codeBlock, ok := child.(*ast.FencedCodeBlock); ok {
+			tab := NewTab(codeBlockTabLabel(codeBlock, source))
+			if options := ParseCodeBlockOptions(codeBlockInfo(codeBlock, source)); options.Sync != "" {
+				tab.Value = options.Sync
+			} else if options.Title == "" && options.Language != "" {
+				tab.Value = options.Language
+			}
+			// the title is used as the label, so it is not repeated as a caption
+			codeBlock.SetAttributeString("tab", true)
+
+			n.ReplaceChild(n, codeBlock, tab)
+			tab.AppendChild(tab, codeBlock)
+		}
+
+		child = next
+	}
+}
+
+func codeBlockTabLabel(codeBlock *ast.FencedCodeBlock, source []byte) string {
+	options := ParseCodeBlockOptions(codeBlockInfo(codeBlock, source))
+
+	if options.Title != "" {
+		return options.Title
+	}
+
+	if lexer := lexers.Get(options.Language); lexer != nil {
+		return lexer.Config().Name
+	}
+
+	if options.Language != "" {
+		return options.Language
+	}
+
+	return "Code"
+}
+
+func parseTabsKey(value string) string {
+	for _, match := range codeBlockOptionRegExp.FindAllStringSubmatch(value, -1) {
+		if match[1] == "key" {
+			return match[2] + match[3]
+		}
+	}
+
+	return ""
+}
+
+func nextTabsID(pc parser.Context) int {
+	id, _ := pc.Get(tabsIDKey).(int)
+	pc.Set(tabsIDKey, id+1)
+
+	return id
+}
+
+type TabsRenderer struct{}
+
+func (r TabsRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTabs, r.renderTabs)
+	reg.Register(KindTab, r.renderTab)
+}
+
+func (r TabsRenderer) renderTabs(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*Tabs)
+
+	if !entering {
+		w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString("<div class=\"tabs\"")
+	if n.Key != "" {
+		w.WriteString(" data-tabs-key=\"")
+		w.Write(util.EscapeHTML([]byte(n.Key)))
+		w.WriteString("\"")
+	}
+	w.WriteString(">\n<div class=\"tabs-list\" role=\"tablist\">\n")
+
+	index := 0
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		tab, ok := child.(*Tab)
+		if !ok {
+			continue
+		}
+
+		selected := index == 0
+		tabIndex := "-1"
+		if selected {
+			tabIndex = "0"
+		}
+
+		fmt.Fprintf(w, "<button type=\"button\" class=\"tabs-tab\" role=\"tab\" id=\"tab-%d-%d\" aria-controls=\"tabpanel-%d-%d\" aria-selected=\"%t\" tabindex=\"%s\" data-tab-value=\"", n.ID, index, n.ID, index, selected, tabIndex)
+		w.Write(util.EscapeHTML([]byte(tab.Value)))
+		w.WriteString("\">")
+		w.Write(util.EscapeHTML([]byte(tab.Label)))
+		w.WriteString("</button>\n")
+
+		index++
+	}
+
+	w.WriteString("</div>\n")
+
+	return ast.WalkContinue, nil
+}
+
+func (r TabsRenderer) renderTab(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	tabs, ok := node.Parent().(*Tabs)
+	if !ok {
+		w.WriteString("<div class=\"tabs-panel\">\n")
+		return ast.WalkContinue, nil
+	}
+
+	index := 0
+	for child := tabs.FirstChild(); child != nil && child != node; child = child.NextSibling() {
+		if _, ok := child.(*Tab); ok {
+			index++
+		}
+	}
+
+	fmt.Fprintf(w, "<div class=\"tabs-panel\" role=\"tabpanel\" id=\"tabpanel-%d-%d\" aria-labelledby=\"tab-%d-%d\" tabindex=\"0\"", tabs.ID, index, tabs.ID, index)
+	if index > 0 {
+		w.WriteString(" hidden")
+	}
+	w.WriteString(">\n")
+
+	return ast.WalkContinue, nil
+}
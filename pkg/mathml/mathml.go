@@ -0,0 +1,648 @@
+package mathml
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Convert converts a TeX formula to a MathML math element. It supports the
+// subset of TeX that is commonly used in documentation: scripts, fractions,
+// roots, accents, fonts, delimiters, matrices and aligned equations.
+func Convert(tex string, display bool) (string, error) {
+	p := &parser{src: tex, display: display}
+
+	body, err := p.table("", "")
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		b.WriteString(` display="block"`)
+	}
+	b.WriteString(`><semantics>`)
+	b.WriteString(body)
+	b.WriteString(`<annotation encoding="application/x-tex">`)
+	b.WriteString(html.EscapeString(tex))
+	b.WriteString(`</annotation></semantics></math>`)
+
+	return b.String(), nil
+}
+
+type parser struct {
+	src     string
+	pos     int
+	display bool
+}
+
+const (
+	endOfInput = ""
+	endGroup   = "}"
+	endCell    = "&"
+	endRow     = `\\`
+	endEnv     = `\end`
+	endRight   = `\right`
+)
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.src) {
+		return 0
+	}
+
+	return p.src[p.pos]
+}
+
+// command reads a control sequence, the backslash has to be at the current
+// position.
+func (p *parser) command() string {
+	start := p.pos
+	p.pos++
+
+	if p.pos >= len(p.src) {
+		return `\`
+	}
+
+	if !isLetter(p.src[p.pos]) {
+		p.pos++
+		return p.src[start:p.pos]
+	}
+
+	for p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+// peekCommand returns the control sequence at the current position without
+// consuming it.
+func (p *parser) peekCommand() string {
+	if p.peek() != '\\' {
+		return ""
+	}
+
+	pos := p.pos
+	cmd := p.command()
+	p.pos = pos
+
+	return cmd
+}
+
+// row parses atoms until one of the terminators is found and returns the
+// atoms and the terminator.
+func (p *parser) row() ([]string, string, error) {
+	atoms := []string{}
+
+	for {
+		p.skipSpace()
+
+		switch c := p.peek(); {
+		case c == 0:
+			return atoms, endOfInput, nil
+		case c == '}':
+			p.pos++
+			return atoms, endGroup, nil
+		case c == '&':
+			p.pos++
+			return atoms, endCell, nil
+		case c == '\\':
+			switch cmd := p.peekCommand(); cmd {
+			case endRow, `\cr`, endEnv, endRight:
+				p.command()
+				return atoms, cmd, nil
+			}
+		}
+
+		atom, err := p.atom()
+		if err != nil {
+			return nil, "", err
+		}
+
+		atoms = append(atoms, atom)
+	}
+}
+
+// group parses a row that must be closed by a closing brace.
+func (p *parser) group() (string, error) {
+	atoms, end, err := p.row()
+	if err != nil {
+		return "", err
+	}
+
+	if end != endGroup {
+		return "", p.errorf("missing }")
+	}
+
+	return mrow(atoms), nil
+}
+
+// table parses rows separated by \\ and cells separated by &, until the end
+// of the input or \end{name}. A single cell is returned as a row.
+func (p *parser) table(env string, columnAlign string) (string, error) {
+	rows := [][]string{}
+	cells := []string{}
+
+	for {
+		atoms, end, err := p.row()
+		if err != nil {
+			return "", err
+		}
+
+		cells = append(cells, mrow(atoms))
+
+		switch end {
+		case endCell:
+			continue
+		case endRow, `\cr`:
+			rows = append(rows, cells)
+			cells = []string{}
+			continue
+		case endEnv:
+			name, err := p.envName()
+			if err != nil {
+				return "", err
+			}
+			if name != env {
+				return "", p.errorf(`\end{%s} does not match \begin{%s}`, name, env)
+			}
+		case endOfInput:
+			if env != "" {
+				return "", p.errorf(`missing \end{%s}`, env)
+			}
+		case endGroup:
+			return "", p.errorf("unexpected }")
+		default:
+			return "", p.errorf("unexpected %s", end)
+		}
+
+		break
+	}
+
+	// a trailing \\ does not start a new row
+	if len(cells) != 1 || cells[0] != "<mrow></mrow>" || len(rows) == 0 {
+		rows = append(rows, cells)
+	}
+
+	if len(rows) == 1 && len(rows[0]) == 1 && env == "" {
+		return rows[0][0], nil
+	}
+
+	var b strings.Builder
+
+	b.WriteString("<mtable")
+	if columnAlign != "" {
+		b.WriteString(` columnalign="` + columnAlign + `"`)
+	}
+	b.WriteString(">")
+	for _, row := range rows {
+		b.WriteString("<mtr>")
+		for _, cell := range row {
+			b.WriteString("<mtd>" + cell + "</mtd>")
+		}
+		b.WriteString("</mtr>")
+	}
+	b.WriteString("</mtable>")
+
+	return b.String(), nil
+}
+
+func (p *parser) envName() (string, error) {
+	p.skipSpace()
+
+	if p.peek() != '{' {
+		return "", p.errorf("missing environment name")
+	}
+
+	end := strings.IndexByte(p.src[p.pos:], '}')
+	if end < 0 {
+		return "", p.errorf("missing }")
+	}
+
+	name := p.src[p.pos+1 : p.pos+end]
+	p.pos += end + 1
+
+	return name, nil
+}
+
+// atom parses a single element with its sub- and superscripts.
+func (p *parser) atom() (string, error) {
+	base, largeOp, err := p.base()
+	if err != nil {
+		return "", err
+	}
+
+	var sub, sup string
+
+	for {
+		p.skipSpace()
+
+		c := p.peek()
+		if c != '^' && c != '_' && c != '\'' {
+			break
+		}
+
+		p.pos++
+
+		if c == '\'' {
+			sup += "<mo>′</mo>"
+			continue
+		}
+
+		arg, err := p.argument()
+		if err != nil {
+			return "", err
+		}
+
+		if c == '^' {
+			sup += arg
+		} else {
+			sub += arg
+		}
+	}
+
+	under, over := "msub", "msup"
+	both := "msubsup"
+	// limits are only placed above and below in display mode
+	if largeOp && p.display {
+		under, over, both = "munder", "mover", "munderover"
+	}
+
+	switch {
+	case sub != "" && sup != "":
+		return fmt.Sprintf("<%s>%s%s%s</%s>", both, base, wrap(sub), wrap(sup), both), nil
+	case sub != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", under, base, wrap(sub), under), nil
+	case sup != "":
+		return fmt.Sprintf("<%s>%s%s</%s>", over, base, wrap(sup), over), nil
+	}
+
+	return base, nil
+}
+
+// argument parses a command argument or script, which is either a group or a
+// single character or command.
+func (p *parser) argument() (string, error) {
+	p.skipSpace()
+
+	c := p.peek()
+
+	switch {
+	case c == 0:
+		return "", p.errorf("missing argument")
+	case c == '{':
+		p.pos++
+		return p.group()
+	case isDigit(c):
+		p.pos++
+		return "<mn>" + string(c) + "</mn>", nil
+	case isLetter(c):
+		p.pos++
+		return "<mi>" + string(c) + "</mi>", nil
+	}
+
+	base, _, err := p.base()
+
+	return base, err
+}
+
+// textArgument reads a braced argument verbatim.
+func (p *parser) textArgument() (string, error) {
+	p.skipSpace()
+
+	if p.peek() != '{' {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("missing argument")
+		}
+		p.pos++
+		return p.src[p.pos-1 : p.pos], nil
+	}
+
+	depth := 0
+	for i := p.pos; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				text := p.src[p.pos+1 : i]
+				p.pos = i + 1
+				return text, nil
+			}
+		}
+	}
+
+	return "", p.errorf("missing }")
+}
+
+// base parses an element without scripts. largeOp is set for operators that
+// take their limits above and below in display mode.
+func (p *parser) base() (string, bool, error) {
+	c := p.peek()
+
+	switch {
+	case c == '{':
+		p.pos++
+		group, err := p.group()
+		return group, false, err
+	case c == '\\':
+		return p.control(p.command())
+	case isDigit(c) || (c == '.' && p.pos+1 < len(p.src) && isDigit(p.src[p.pos+1])):
+		start := p.pos
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return "<mn>" + p.src[start:p.pos] + "</mn>", false, nil
+	case isLetter(c):
+		p.pos++
+		return "<mi>" + string(c) + "</mi>", false, nil
+	case c == '~':
+		p.pos++
+		return `<mspace width="0.33em"></mspace>`, false, nil
+	case c == '^' || c == '_':
+		return "", false, p.errorf("unexpected %c", c)
+	}
+
+	// any other character, including multibyte characters, is an operator
+	r := []rune(p.src[p.pos:])[0]
+	p.pos += len(string(r))
+
+	if op, ok := charOperators[r]; ok {
+		return "<mo>" + op + "</mo>", false, nil
+	}
+
+	if unicode.IsLetter(r) {
+		return "<mi>" + string(r) + "</mi>", false, nil
+	}
+
+	return "<mo>" + html.EscapeString(string(r)) + "</mo>", false, nil
+}
+
+// control converts a control sequence.
+func (p *parser) control(cmd string) (string, bool, error) {
+	name := cmd[1:]
+
+	if symbol, ok := identifiers[name]; ok {
+		if unicode.IsUpper([]rune(symbol)[0]) {
+			return `<mi mathvariant="normal">` + symbol + "</mi>", false, nil
+		}
+		return "<mi>" + symbol + "</mi>", false, nil
+	}
+
+	if symbol, ok := operators[name]; ok {
+		return "<mo>" + symbol + "</mo>", false, nil
+	}
+
+	if symbol, ok := largeOperators[name]; ok {
+		return "<mo>" + symbol + "</mo>", true, nil
+	}
+
+	if _, ok := functions[name]; ok {
+		return "<mi>" + name + "</mi>", false, nil
+	}
+
+	if _, ok := limitFunctions[name]; ok {
+		return "<mi>" + name + "</mi>", true, nil
+	}
+
+	if width, ok := spaces[name]; ok {
+		return `<mspace width="` + width + `"></mspace>`, false, nil
+	}
+
+	if variant, ok := fonts[name]; ok {
+		arg, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		return `<mstyle mathvariant="` + variant + `">` + arg + "</mstyle>", false, nil
+	}
+
+	if accent, ok := accents[name]; ok {
+		arg, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		return `<mover accent="true">` + arg + "<mo>" + accent + "</mo></mover>", false, nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "binom":
+		numerator, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		denominator, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		if name == "binom" {
+			return `<mrow><mo>(</mo><mfrac linethickness="0">` + numerator + denominator + `</mfrac><mo>)</mo></mrow>`, false, nil
+		}
+		return "<mfrac>" + numerator + denominator + "</mfrac>", false, nil
+	case "sqrt":
+		p.skipSpace()
+		if p.peek() == '[' {
+			end := strings.IndexByte(p.src[p.pos:], ']')
+			if end < 0 {
+				return "", false, p.errorf("missing ]")
+			}
+			index, err := Convert(p.src[p.pos+1:p.pos+end], false)
+			if err != nil {
+				return "", false, err
+			}
+			p.pos += end + 1
+			arg, err := p.argument()
+			if err != nil {
+				return "", false, err
+			}
+			return "<mroot>" + arg + innerMath(index) + "</mroot>", false, nil
+		}
+		arg, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		return "<msqrt>" + arg + "</msqrt>", false, nil
+	case "text", "textrm", "textit", "textbf", "mbox":
+		text, err := p.textArgument()
+		if err != nil {
+			return "", false, err
+		}
+		return "<mtext>" + html.EscapeString(text) + "</mtext>", false, nil
+	case "operatorname":
+		text, err := p.textArgument()
+		if err != nil {
+			return "", false, err
+		}
+		return "<mi>" + html.EscapeString(text) + "</mi>", false, nil
+	case "overline", "underline":
+		arg, err := p.argument()
+		if err != nil {
+			return "", false, err
+		}
+		if name == "overline" {
+			return `<mover accent="true">` + arg + "<mo>‾</mo></mover>", false, nil
+		}
+		return `<munder accentunder="true">` + arg + "<mo>_</mo></munder>", false, nil
+	case "left":
+		open, err := p.delimiter()
+		if err != nil {
+			return "", false, err
+		}
+		atoms, end, err := p.row()
+		if err != nil {
+			return "", false, err
+		}
+		if end != endRight {
+			return "", false, p.errorf(`missing \right`)
+		}
+		closing, err := p.delimiter()
+		if err != nil {
+			return "", false, err
+		}
+		return "<mrow>" + fence(open) + strings.Join(atoms, "") + fence(closing) + "</mrow>", false, nil
+	case "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "biggl", "biggr", "Biggl", "Biggr":
+		delimiter, err := p.delimiter()
+		if err != nil {
+			return "", false, err
+		}
+		return "<mo>" + delimiter + "</mo>", false, nil
+	case "begin":
+		env, err := p.envName()
+		if err != nil {
+			return "", false, err
+		}
+		return p.environment(env)
+	case "displaystyle", "textstyle", "limits", "nolimits":
+		return "", false, nil
+	}
+
+	return "", false, p.errorf("unknown command %s", cmd)
+}
+
+func (p *parser) environment(env string) (string, bool, error) {
+	columnAlign := ""
+	open, closing := "", ""
+
+	switch env {
+	case "matrix", "smallmatrix":
+	case "pmatrix":
+		open, closing = "(", ")"
+	case "bmatrix":
+		open, closing = "[", "]"
+	case "Bmatrix":
+		open, closing = "{", "}"
+	case "vmatrix":
+		open, closing = "|", "|"
+	case "Vmatrix":
+		open, closing = "‖", "‖"
+	case "cases":
+		open = "{"
+		columnAlign = "left left"
+	case "aligned", "align", "align*", "split", "gathered", "alignat", "alignat*":
+		columnAlign = "right left right left right left"
+	case "array":
+		// the column specification is not used
+		if _, err := p.textArgument(); err != nil {
+			return "", false, err
+		}
+	default:
+		return "", false, p.errorf("unknown environment %s", env)
+	}
+
+	table, err := p.table(env, columnAlign)
+	if err != nil {
+		return "", false, err
+	}
+
+	if open == "" && closing == "" {
+		return table, false, nil
+	}
+
+	result := "<mrow>"
+	if open != "" {
+		result += fence(open)
+	}
+	result += table
+	if closing != "" {
+		result += fence(closing)
+	}
+
+	return result + "</mrow>", false, nil
+}
+
+// delimiter reads the delimiter after \left, \right or \big.
+func (p *parser) delimiter() (string, error) {
+	p.skipSpace()
+
+	c := p.peek()
+	if c == 0 {
+		return "", p.errorf("missing delimiter")
+	}
+
+	if c == '\\' {
+		cmd := p.command()
+		if symbol, ok := delimiters[cmd[1:]]; ok {
+			return symbol, nil
+		}
+		return "", p.errorf("unknown delimiter %s", cmd)
+	}
+
+	p.pos++
+
+	if c == '.' {
+		return "", nil
+	}
+
+	return html.EscapeString(string(c)), nil
+}
+
+func fence(delimiter string) string {
+	if delimiter == "" {
+		return ""
+	}
+
+	return `<mo fence="true" stretchy="true">` + delimiter + "</mo>"
+}
+
+func mrow(atoms []string) string {
+	return "<mrow>" + strings.Join(atoms, "") + "</mrow>"
+}
+
+// wrap groups multiple script elements into a single row.
+func wrap(elements string) string {
+	if strings.HasPrefix(elements, "<mrow>") && strings.HasSuffix(elements, "</mrow>") {
+		return elements
+	}
+
+	return "<mrow>" + elements + "</mrow>"
+}
+
+// innerMath strips the math element produced by Convert.
+func innerMath(math string) string {
+	start := strings.Index(math, "<semantics>") + len("<semantics>")
+	end := strings.Index(math, "<annotation")
+
+	return math[start:end]
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,79 @@
+package mathml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		tex  string
+		want string
+	}{
+		{"fraction", `\frac{a}{b}`, `<mrow><mfrac><mrow><mi>a</mi></mrow><mrow><mi>b</mi></mrow></mfrac></mrow>`},
+		{"superscript", `x^2`, `<mrow><msup><mi>x</mi><mrow><mn>2</mn></mrow></msup></mrow>`},
+		{"subscript and superscript", `x_i^2`, `<mrow><msubsup><mi>x</mi><mrow><mi>i</mi></mrow><mrow><mn>2</mn></mrow></msubsup></mrow>`},
+		{"root", `\sqrt{2}`, `<mrow><msqrt><mrow><mn>2</mn></mrow></msqrt></mrow>`},
+		{"symbol", `\alpha`, `<mrow><mi>α</mi></mrow>`},
+		{"delimiters", `\left( x \right)`, `<mrow><mrow><mo fence="true" stretchy="true">(</mo><mi>x</mi><mo fence="true" stretchy="true">)</mo></mrow></mrow>`},
+		{"matrix", `\begin{pmatrix}1&2\\3&4\end{pmatrix}`, `<mrow><mrow><mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mrow><mn>1</mn></mrow></mtd><mtd><mrow><mn>2</mn></mrow></mtd></mtr><mtr><mtd><mrow><mn>3</mn></mrow></mtd><mtd><mrow><mn>4</mn></mrow></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow></mrow>`},
+		{"escaped operator", `a<b`, `<mrow><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`},
+		{"escaped text", `\text{a<b & c}`, `<mrow><mtext>a&lt;b &amp; c</mtext></mrow>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.tex, false)
+			if err != nil {
+				t.Fatalf("Convert(%q): %v", tt.tex, err)
+			}
+
+			body := strings.TrimPrefix(got, `<math xmlns="http://www.w3.org/1998/Math/MathML"><semantics>`)
+			body, _, _ = strings.Cut(body, `<annotation`)
+			if body != tt.want {
+				t.Errorf("Convert(%q) =\n%s\nwant\n%s", tt.tex, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertAnnotation(t *testing.T) {
+	got, err := Convert(`a<b & c`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(got, `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`) {
+		t.Errorf("display math is not a block: %s", got)
+	}
+	if !strings.Contains(got, `<annotation encoding="application/x-tex">a&lt;b &amp; c</annotation>`) {
+		t.Errorf("annotation does not have the escaped TeX: %s", got)
+	}
+}
+
+func TestConvertMalformed(t *testing.T) {
+	tests := []struct {
+		tex  string
+		want string
+	}{
+		{`\frac{a}`, "missing argument at position 8"},
+		{`x^`, "missing argument at position 2"},
+		{`{a`, "missing } at position 2"},
+		{`}`, "unexpected } at position 1"},
+		{`\nope`, `unknown command \nope at position 5`},
+		{`\begin{matrix}1\end{pmatrix}`, `\end{pmatrix} does not match \begin{matrix} at position 28`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tex, func(t *testing.T) {
+			got, err := Convert(tt.tex, false)
+			if err == nil {
+				t.Fatalf("Convert(%q) = %s, want error %q", tt.tex, got, tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("Convert(%q) error = %q, want %q", tt.tex, err, tt.want)
+			}
+		})
+	}
+}
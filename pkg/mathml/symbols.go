@@ -0,0 +1,255 @@
+package mathml
+
+// charOperators maps characters that are written differently in MathML.
+var charOperators = map[rune]string{
+	'-': "−",
+	'*': "∗",
+	'<': "&lt;",
+	'>': "&gt;",
+	'&': "&amp;",
+}
+
+var identifiers = map[string]string{
+	"alpha":      "α",
+	"beta":       "β",
+	"gamma":      "γ",
+	"delta":      "δ",
+	"epsilon":    "ϵ",
+	"varepsilon": "ε",
+	"zeta":       "ζ",
+	"eta":        "η",
+	"theta":      "θ",
+	"vartheta":   "ϑ",
+	"iota":       "ι",
+	"kappa":      "κ",
+	"lambda":     "λ",
+	"mu":         "μ",
+	"nu":         "ν",
+	"xi":         "ξ",
+	"pi":         "π",
+	"varpi":      "ϖ",
+	"rho":        "ρ",
+	"varrho":     "ϱ",
+	"sigma":      "σ",
+	"varsigma":   "ς",
+	"tau":        "τ",
+	"upsilon":    "υ",
+	"phi":        "ϕ",
+	"varphi":     "φ",
+	"chi":        "χ",
+	"psi":        "ψ",
+	"omega":      "ω",
+	"Gamma":      "Γ",
+	"Delta":      "Δ",
+	"Theta":      "Θ",
+	"Lambda":     "Λ",
+	"Xi":         "Ξ",
+	"Pi":         "Π",
+	"Sigma":      "Σ",
+	"Upsilon":    "Υ",
+	"Phi":        "Φ",
+	"Psi":        "Ψ",
+	"Omega":      "Ω",
+	"infty":      "∞",
+	"partial":    "∂",
+	"nabla":      "∇",
+	"emptyset":   "∅",
+	"varnothing": "∅",
+	"hbar":       "ℏ",
+	"ell":        "ℓ",
+	"Re":         "ℜ",
+	"Im":         "ℑ",
+	"aleph":      "ℵ",
+	"imath":      "ı",
+	"jmath":      "ȷ",
+}
+
+var operators = map[string]string{
+	"pm":              "±",
+	"mp":              "∓",
+	"times":           "×",
+	"div":             "÷",
+	"cdot":            "⋅",
+	"ast":             "∗",
+	"star":            "⋆",
+	"circ":            "∘",
+	"bullet":          "∙",
+	"oplus":           "⊕",
+	"otimes":          "⊗",
+	"cdots":           "⋯",
+	"ldots":           "…",
+	"dots":            "…",
+	"vdots":           "⋮",
+	"ddots":           "⋱",
+	"leq":             "≤",
+	"le":              "≤",
+	"geq":             "≥",
+	"ge":              "≥",
+	"neq":             "≠",
+	"ne":              "≠",
+	"ll":              "≪",
+	"gg":              "≫",
+	"approx":          "≈",
+	"equiv":           "≡",
+	"sim":             "∼",
+	"simeq":           "≃",
+	"cong":            "≅",
+	"propto":          "∝",
+	"in":              "∈",
+	"notin":           "∉",
+	"ni":              "∋",
+	"subset":          "⊂",
+	"subseteq":        "⊆",
+	"supset":          "⊃",
+	"supseteq":        "⊇",
+	"cup":             "∪",
+	"cap":             "∩",
+	"setminus":        "∖",
+	"forall":          "∀",
+	"exists":          "∃",
+	"nexists":         "∄",
+	"neg":             "¬",
+	"lnot":            "¬",
+	"land":            "∧",
+	"wedge":           "∧",
+	"lor":             "∨",
+	"vee":             "∨",
+	"to":              "→",
+	"rightarrow":      "→",
+	"leftarrow":       "←",
+	"gets":            "←",
+	"leftrightarrow":  "↔",
+	"Rightarrow":      "⇒",
+	"Leftarrow":       "⇐",
+	"Leftrightarrow":  "⇔",
+	"implies":         "⟹",
+	"impliedby":       "⟸",
+	"iff":             "⟺",
+	"mapsto":          "↦",
+	"longrightarrow":  "⟶",
+	"longleftarrow":   "⟵",
+	"uparrow":         "↑",
+	"downarrow":       "↓",
+	"langle":          "⟨",
+	"rangle":          "⟩",
+	"lfloor":          "⌊",
+	"rfloor":          "⌋",
+	"lceil":           "⌈",
+	"rceil":           "⌉",
+	"{":               "{",
+	"}":               "}",
+	"|":               "‖",
+	"mid":             "∣",
+	"perp":            "⊥",
+	"parallel":        "∥",
+	"angle":           "∠",
+	"prime":           "′",
+	"top":             "⊤",
+	"bot":             "⊥",
+	"vdash":           "⊢",
+	"models":          "⊨",
+	"triangle":        "△",
+	"backslash":       "∖",
+	"colon":           ":",
+	"%":               "%",
+	"$":               "$",
+	"#":               "#",
+	"_":               "_",
+	"&":               "&amp;",
+	"lbrace":          "{",
+	"rbrace":          "}",
+	"vert":            "|",
+	"Vert":            "‖",
+	"coloneqq":        "≔",
+	"leftrightarrows": "⇄",
+}
+
+var largeOperators = map[string]string{
+	"sum":       "∑",
+	"prod":      "∏",
+	"coprod":    "∐",
+	"int":       "∫",
+	"iint":      "∬",
+	"iiint":     "∭",
+	"oint":      "∮",
+	"bigcup":    "⋃",
+	"bigcap":    "⋂",
+	"bigvee":    "⋁",
+	"bigwedge":  "⋀",
+	"bigoplus":  "⨁",
+	"bigotimes": "⨂",
+}
+
+var functions = map[string]struct{}{
+	"sin": {}, "cos": {}, "tan": {}, "sec": {}, "csc": {}, "cot": {},
+	"arcsin": {}, "arccos": {}, "arctan": {},
+	"sinh": {}, "cosh": {}, "tanh": {}, "coth": {},
+	"log": {}, "ln": {}, "lg": {}, "exp": {},
+	"arg": {}, "deg": {}, "dim": {}, "hom": {}, "ker": {},
+}
+
+var limitFunctions = map[string]struct{}{
+	"lim": {}, "liminf": {}, "limsup": {},
+	"max": {}, "min": {}, "sup": {}, "inf": {},
+	"det": {}, "gcd": {}, "Pr": {}, "argmax": {}, "argmin": {},
+}
+
+var spaces = map[string]string{
+	",":     "0.17em",
+	":":     "0.22em",
+	">":     "0.22em",
+	";":     "0.28em",
+	" ":     "0.33em",
+	"!":     "-0.17em",
+	"quad":  "1em",
+	"qquad": "2em",
+}
+
+var fonts = map[string]string{
+	"mathrm":     "normal",
+	"mathit":     "italic",
+	"mathbf":     "bold",
+	"boldsymbol": "bold-italic",
+	"mathbb":     "double-struck",
+	"mathcal":    "script",
+	"mathscr":    "script",
+	"mathfrak":   "fraktur",
+	"mathsf":     "sans-serif",
+	"mathtt":     "monospace",
+}
+
+var accents = map[string]string{
+	"hat":            "^",
+	"widehat":        "^",
+	"bar":            "¯",
+	"vec":            "→",
+	"overrightarrow": "→",
+	"dot":            "˙",
+	"ddot":           "¨",
+	"tilde":          "~",
+	"widetilde":      "~",
+	"check":          "ˇ",
+	"breve":          "˘",
+	"acute":          "´",
+	"grave":          "`",
+}
+
+var delimiters = map[string]string{
+	"{":      "{",
+	"}":      "}",
+	"|":      "‖",
+	"lbrace": "{",
+	"rbrace": "}",
+	"langle": "⟨",
+	"rangle": "⟩",
+	"lfloor": "⌊",
+	"rfloor": "⌋",
+	"lceil":  "⌈",
+	"rceil":  "⌉",
+	"vert":   "|",
+	"Vert":   "‖",
+	"lvert":  "|",
+	"rvert":  "|",
+	"lVert":  "‖",
+	"rVert":  "‖",
+}
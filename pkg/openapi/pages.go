@@ -0,0 +1,782 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var slugRegExp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Options control how the pages of a specification are generated.
+type Options struct {
+	// GroupBy is "tag" for a page per tag or "operation" for a page per
+	// operation.
+	GroupBy string
+	// Markdown converts the CommonMark descriptions to HTML.
+	Markdown func(source string) string
+	// Link returns the link to the page with the slug.
+	Link func(slug string) string
+}
+
+// Page is a generated reference page. The overview page has the slug index.
+type Page struct {
+	Slug        string
+	Title       string
+	Description string
+	HTML        string
+}
+
+type group struct {
+	slug        string
+	title       string
+	description string
+	operations  []*Operation
+}
+
+type renderer struct {
+	spec     *Spec
+	opts     Options
+	buf      bytes.Buffer
+	warnings []string
+	// anchors are the pages and anchors of the operations
+	anchors map[*Operation]string
+	pages   map[*Operation]string
+}
+
+// Pages generates the overview page, a page per tag or operation and a page
+// for the schemas of the components. The warnings are references that could
+// not be resolved.
+func (s *Spec) Pages(opts Options) ([]Page, []string, error) {
+	if opts.GroupBy == "" {
+		opts.GroupBy = "tag"
+	}
+	if opts.GroupBy != "tag" && opts.GroupBy != "operation" {
+		return nil, nil, fmt.Errorf("Unknown OpenAPI grouping %q, expected tag or operation", opts.GroupBy)
+	}
+
+	r := &renderer{spec: s, opts: opts, anchors: map[*Operation]string{}, pages: map[*Operation]string{}}
+
+	operations := s.Operations()
+	groups := r.groups(operations)
+
+	pages := []Page{r.overview(operations)}
+
+	for _, g := range groups {
+		r.buf.Reset()
+
+		if opts.GroupBy == "operation" {
+			r.operation(g.operations[0], 1)
+		} else {
+			fmt.Fprintf(&r.buf, "<h1>%s</h1>\n", html.EscapeString(g.title))
+			r.markdown(g.description)
+			for _, op := range g.operations {
+				r.operation(op, 2)
+			}
+		}
+
+		pages = append(pages, Page{
+			Slug:        g.slug,
+			Title:       g.title,
+			Description: firstLine(g.description),
+			HTML:        r.buf.String(),
+		})
+	}
+
+	if len(s.Components.Schemas) > 0 {
+		pages = append(pages, r.schemas())
+	}
+
+	return pages, r.warnings, nil
+}
+
+func (r *renderer) groups(operations []*Operation) []*group {
+	groups := []*group{}
+	slugs := map[string]bool{"index": true, "schemas": true}
+
+	uniqueSlug := func(title string) string {
+		base := slug(title)
+		if base == "" {
+			base = "operation"
+		}
+		s := base
+		for i := 2; slugs[s]; i++ {
+			s = fmt.Sprintf("%s-%d", base, i)
+		}
+		slugs[s] = true
+		return s
+	}
+
+	if r.opts.GroupBy == "operation" {
+		for _, op := range operations {
+			name := op.OperationID
+			if name == "" {
+				name = op.Method + " " + op.Path
+			}
+
+			g := &group{slug: uniqueSlug(name), title: operationTitle(op), description: op.Description, operations: []*Operation{op}}
+			r.pages[op] = g.slug
+			r.anchors[op] = operationAnchor(op)
+			groups = append(groups, g)
+		}
+
+		return groups
+	}
+
+	byTag := map[string]*group{}
+	addTag := func(name string, description string) {
+		if _, ok := byTag[name]; !ok {
+			byTag[name] = &group{slug: uniqueSlug(name), title: name, description: description}
+			groups = append(groups, byTag[name])
+		}
+	}
+
+	for _, tag := range r.spec.Tags {
+		addTag(tag.Name, tag.Description)
+	}
+
+	for _, op := range operations {
+		tags := op.Tags
+		if len(tags) == 0 {
+			tags = []string{"Other"}
+		}
+
+		for _, tag := range tags {
+			addTag(tag, "")
+			byTag[tag].operations = append(byTag[tag].operations, op)
+
+			if _, ok := r.pages[op]; !ok {
+				r.pages[op] = byTag[tag].slug
+				r.anchors[op] = operationAnchor(op)
+			}
+		}
+	}
+
+	// tags without operations have nothing to show
+	nonEmpty := []*group{}
+	for _, g := range groups {
+		if len(g.operations) > 0 {
+			nonEmpty = append(nonEmpty, g)
+		}
+	}
+
+	return nonEmpty
+}
+
+func (r *renderer) overview(operations []*Operation) Page {
+	r.buf.Reset()
+
+	info := r.spec.Info
+
+	fmt.Fprintf(&r.buf, "<h1>%s</h1>\n", html.EscapeString(info.Title))
+	if info.Version != "" {
+		fmt.Fprintf(&r.buf, "<p><span class=\"badge\">%s</span></p>\n", html.EscapeString(info.Version))
+	}
+	r.markdown(info.Description)
+
+	if len(r.spec.Servers) > 0 {
+		r.buf.WriteString("<h2>Servers</h2>\n<ul>\n")
+		for _, server := range r.spec.Servers {
+			fmt.Fprintf(&r.buf, "<li><code>%s</code>", html.EscapeString(server.URL))
+			if server.Description != "" {
+				fmt.Fprintf(&r.buf, " %s", html.EscapeString(server.Description))
+			}
+			r.buf.WriteString("</li>\n")
+		}
+		r.buf.WriteString("</ul>\n")
+	}
+
+	if len(operations) > 0 {
+		r.buf.WriteString("<h2>Operations</h2>\n<table>\n<thead><tr><th>Endpoint</th><th>Summary</th></tr></thead>\n<tbody>\n")
+		for _, op := range operations {
+			fmt.Fprintf(&r.buf, "<tr><td><a href=\"%s#%s\">", html.EscapeString(r.opts.Link(r.pages[op])), r.anchors[op])
+			r.endpoint(op)
+			fmt.Fprintf(&r.buf, "</a></td><td>%s</td></tr>\n", html.EscapeString(op.Summary))
+		}
+		r.buf.WriteString("</tbody>\n</table>\n")
+	}
+
+	return Page{
+		Slug:        "index",
+		Title:       info.Title,
+		Description: firstLine(info.Description),
+		HTML:        r.buf.String(),
+	}
+}
+
+func (r *renderer) schemas() Page {
+	r.buf.Reset()
+
+	r.buf.WriteString("<h1>Schemas</h1>\n")
+
+	names := make([]string, 0, len(r.spec.Components.Schemas))
+	for name := range r.spec.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		schema := r.spec.Components.Schemas[name]
+
+		fmt.Fprintf(&r.buf, "<h2 id=\"schema-%s\">%s</h2>\n", slug(name), html.EscapeString(name))
+		r.schemaBlock(schema, map[string]bool{name: true})
+	}
+
+	return Page{
+		Slug:  "schemas",
+		Title: "Schemas",
+		HTML:  r.buf.String(),
+	}
+}
+
+func (r *renderer) operation(op *Operation, level int) {
+	fmt.Fprintf(&r.buf, "<h%d id=\"%s\">%s</h%d>\n", level, r.anchors[op], html.EscapeString(operationTitle(op)), level)
+
+	r.buf.WriteString("<p class=\"openapi-endpoint\">")
+	r.endpoint(op)
+	if op.Deprecated {
+		r.buf.WriteString(" <span class=\"badge badge-amber\">deprecated</span>")
+	}
+	r.buf.WriteString("</p>\n")
+
+	r.markdown(op.Description)
+
+	params := []*Parameter{}
+	for _, param := range op.Parameters {
+		if param = r.parameter(param); param != nil {
+			params = append(params, param)
+		}
+	}
+
+	if len(params) > 0 {
+		fmt.Fprintf(&r.buf, "<h%d>Parameters</h%d>\n", level+1, level+1)
+		r.buf.WriteString("<table class=\"openapi-parameters\">\n<thead><tr><th>Name</th><th>In</th><th>Type</th><th>Description</th></tr></thead>\n<tbody>\n")
+		for _, param := range params {
+			fmt.Fprintf(&r.buf, "<tr><td><code>%s</code>", html.EscapeString(param.Name))
+			r.flags(param.Required, param.Deprecated, false, false)
+			fmt.Fprintf(&r.buf, "</td><td>%s</td><td>%s</td><td>", html.EscapeString(param.In), r.typeText(param.Schema))
+			r.markdown(param.Description)
+			r.buf.WriteString("</td></tr>\n")
+		}
+		r.buf.WriteString("</tbody>\n</table>\n")
+	}
+
+	if body := r.requestBody(op.RequestBody); body != nil {
+		fmt.Fprintf(&r.buf, "<h%d>Request body</h%d>\n", level+1, level+1)
+		if body.Required {
+			r.buf.WriteString("<p><span class=\"openapi-required\">required</span></p>\n")
+		}
+		r.markdown(body.Description)
+		r.content(body.Content)
+	}
+
+	if len(op.Responses) > 0 {
+		fmt.Fprintf(&r.buf, "<h%d>Responses</h%d>\n", level+1, level+1)
+
+		codes := make([]string, 0, len(op.Responses))
+		for code := range op.Responses {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+
+		for _, code := range codes {
+			response := r.response(op.Responses[code])
+			if response == nil {
+				continue
+			}
+
+			fmt.Fprintf(&r.buf, "<div class=\"openapi-response\">\n<p><code>%s</code></p>\n", html.EscapeString(code))
+			r.markdown(response.Description)
+			r.content(response.Content)
+			r.buf.WriteString("</div>\n")
+		}
+	}
+}
+
+func (r *renderer) endpoint(op *Operation) {
+	fmt.Fprintf(&r.buf, "<span class=\"openapi-method openapi-method-%s\">%s</span> <code>%s</code>", op.Method, strings.ToUpper(op.Method), html.EscapeString(op.Path))
+}
+
+func (r *renderer) flags(required bool, deprecated bool, readOnly bool, writeOnly bool) {
+	if required {
+		r.buf.WriteString(" <span class=\"openapi-required\">required</span>")
+	}
+	if deprecated {
+		r.buf.WriteString(" <span class=\"openapi-flag\">deprecated</span>")
+	}
+	if readOnly {
+		r.buf.WriteString(" <span class=\"openapi-flag\">read-only</span>")
+	}
+	if writeOnly {
+		r.buf.WriteString(" <span class=\"openapi-flag\">write-only</span>")
+	}
+}
+
+// content writes the schema and example of each media type.
+func (r *renderer) content(content map[string]*MediaType) {
+	mediaTypes := make([]string, 0, len(content))
+	for mediaType := range content {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	for _, mediaType := range mediaTypes {
+		media := content[mediaType]
+		if media == nil {
+			continue
+		}
+
+		fmt.Fprintf(&r.buf, "<p class=\"openapi-media-type\"><code>%s</code>", html.EscapeString(mediaType))
+		if media.Schema != nil {
+			fmt.Fprintf(&r.buf, " <span class=\"openapi-type\">%s</span>", r.typeText(media.Schema))
+		}
+		r.buf.WriteString("</p>\n")
+
+		example := media.Example
+		if example == nil {
+			names := make([]string, 0, len(media.Examples))
+			for name := range media.Examples {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				if ex := r.exampleRef(media.Examples[name]); ex != nil {
+					example = ex.Value
+					break
+				}
+			}
+		}
+
+		if media.Schema != nil {
+			r.schemaTree(media.Schema, map[string]bool{})
+		}
+
+		if example == nil && media.Schema != nil && strings.Contains(mediaType, "json") {
+			example = r.example(media.Schema, map[string]bool{})
+		}
+
+		if example != nil {
+			r.exampleBlock(example)
+		}
+	}
+}
+
+// schemaBlock writes a schema with its description, properties and example.
+func (r *renderer) schemaBlock(schema *Schema, seen map[string]bool) {
+	resolved := r.schema(schema)
+	if resolved == nil {
+		return
+	}
+
+	fmt.Fprintf(&r.buf, "<p class=\"openapi-type\">%s</p>\n", r.typeText(resolved))
+	r.markdown(resolved.Description)
+	r.schemaTree(resolved, seen)
+
+	example := resolved.Example
+	if example == nil {
+		example = r.example(resolved, copySeen(seen))
+	}
+	r.exampleBlock(example)
+}
+
+// schemaTree writes the properties of an object schema, or of the items of
+// an array schema, as a nested list. Referenced schemas are expanded unless
+// they are already being expanded, which stops recursive schemas.
+func (r *renderer) schemaTree(schema *Schema, seen map[string]bool) {
+	if name, ok := refName(schema.Ref, "schemas"); ok {
+		if seen[name] {
+			return
+		}
+		seen = copySeen(seen)
+		seen[name] = true
+	}
+
+	resolved := r.schema(schema)
+	if resolved == nil {
+		return
+	}
+
+	if resolved.Items != nil {
+		r.schemaTree(resolved.Items, seen)
+		return
+	}
+
+	properties, required := r.properties(resolved, seen)
+	if len(properties) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.buf.WriteString("<ul class=\"openapi-schema\">\n")
+	for _, name := range names {
+		property := properties[name]
+		resolvedProperty := r.schema(property)
+		if resolvedProperty == nil {
+			continue
+		}
+
+		fmt.Fprintf(&r.buf, "<li><code>%s</code> <span class=\"openapi-type\">%s</span>", html.EscapeString(name), r.typeText(property))
+		r.flags(required[name], resolvedProperty.Deprecated, resolvedProperty.ReadOnly, resolvedProperty.WriteOnly)
+		if property.Ref == "" {
+			r.markdown(resolvedProperty.Description)
+		}
+		r.schemaTree(property, seen)
+		r.buf.WriteString("</li>\n")
+	}
+	r.buf.WriteString("</ul>\n")
+}
+
+// properties returns the properties of an object schema, including the ones
+// of the schemas in its allOf. Referenced schemas that are already being
+// expanded are skipped, which stops allOf cycles.
+func (r *renderer) properties(schema *Schema, seen map[string]bool) (map[string]*Schema, map[string]bool) {
+	properties := map[string]*Schema{}
+	required := map[string]bool{}
+
+	for name, property := range schema.Properties {
+		properties[name] = property
+	}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	for _, part := range schema.AllOf {
+		partSeen := seen
+		if name, ok := refName(part.Ref, "schemas"); ok {
+			if seen[name] {
+				continue
+			}
+			partSeen = copySeen(seen)
+			partSeen[name] = true
+		}
+
+		if resolved := r.schema(part); resolved != nil {
+			partProperties, partRequired := r.properties(resolved, partSeen)
+			for name, property := range partProperties {
+				properties[name] = property
+			}
+			for name := range partRequired {
+				required[name] = true
+			}
+		}
+	}
+
+	return properties, required
+}
+
+// typeText returns the type of a schema as HTML, with referenced schemas
+// linked to the schemas page.
+func (r *renderer) typeText(schema *Schema) string {
+	if schema == nil {
+		return ""
+	}
+
+	if name, ok := refName(schema.Ref, "schemas"); ok {
+		if _, exists := r.spec.Components.Schemas[name]; exists {
+			return fmt.Sprintf("<a href=\"%s#schema-%s\">%s</a>", html.EscapeString(r.opts.Link("schemas")), slug(name), html.EscapeString(name))
+		}
+	}
+
+	resolved := r.schema(schema)
+	if resolved == nil {
+		return ""
+	}
+
+	alternatives := func(schemas []*Schema, separator string) string {
+		texts := []string{}
+		for _, s := range schemas {
+			texts = append(texts, r.typeText(s))
+		}
+		return strings.Join(texts, separator)
+	}
+
+	var text string
+
+	switch {
+	case len(resolved.OneOf) > 0:
+		text = "one of " + alternatives(resolved.OneOf, " | ")
+	case len(resolved.AnyOf) > 0:
+		text = "any of " + alternatives(resolved.AnyOf, " | ")
+	case len(resolved.AllOf) == 1 && len(resolved.Properties) == 0:
+		text = r.typeText(resolved.AllOf[0])
+	case resolved.Items != nil:
+		text = "array of " + r.typeText(resolved.Items)
+	default:
+		text = html.EscapeString(strings.Join(schemaTypes(resolved), " | "))
+		if text == "" && (len(resolved.Properties) > 0 || len(resolved.AllOf) > 0) {
+			text = "object"
+		}
+		if resolved.Format != "" {
+			text += " (" + html.EscapeString(resolved.Format) + ")"
+		}
+	}
+
+	if resolved.Nullable {
+		text += " | null"
+	}
+
+	if len(resolved.Enum) > 0 {
+		values := []string{}
+		for _, value := range resolved.Enum {
+			values = append(values, "<code>"+html.EscapeString(fmt.Sprint(value))+"</code>")
+		}
+		text += ", one of " + strings.Join(values, ", ")
+	}
+
+	if resolved.Default != nil {
+		text += ", default <code>" + html.EscapeString(fmt.Sprint(resolved.Default)) + "</code>"
+	}
+
+	return text
+}
+
+// example returns the example of a schema, or a value of its type if it does
+// not have one.
+func (r *renderer) example(schema *Schema, seen map[string]bool) interface{} {
+	if name, ok := refName(schema.Ref, "schemas"); ok {
+		if seen[name] {
+			return nil
+		}
+		seen = copySeen(seen)
+		seen[name] = true
+	}
+
+	resolved := r.schema(schema)
+	if resolved == nil {
+		return nil
+	}
+
+	switch {
+	case resolved.Example != nil:
+		return resolved.Example
+	case resolved.Default != nil:
+		return resolved.Default
+	case len(resolved.Enum) > 0:
+		return resolved.Enum[0]
+	case len(resolved.OneOf) > 0:
+		return r.example(resolved.OneOf[0], seen)
+	case len(resolved.AnyOf) > 0:
+		return r.example(resolved.AnyOf[0], seen)
+	case resolved.Items != nil:
+		item := r.example(resolved.Items, seen)
+		if item == nil {
+			return []interface{}{}
+		}
+		return []interface{}{item}
+	}
+
+	if properties, _ := r.properties(resolved, seen); len(properties) > 0 {
+		object := map[string]interface{}{}
+		for name, property := range properties {
+			if value := r.example(property, seen); value != nil {
+				object[name] = value
+			}
+		}
+		return object
+	}
+
+	types := schemaTypes(resolved)
+	if len(types) == 0 {
+		return nil
+	}
+
+	switch types[0] {
+	case "string":
+		switch resolved.Format {
+		case "date-time":
+			return "2024-01-01T00:00:00Z"
+		case "date":
+			return "2024-01-01"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return true
+	case "object":
+		return map[string]interface{}{}
+	case "array":
+		return []interface{}{}
+	}
+
+	return nil
+}
+
+func (r *renderer) exampleBlock(example interface{}) {
+	if example == nil {
+		return
+	}
+
+	var content string
+	if s, ok := example.(string); ok {
+		content = s
+	} else {
+		encoded, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			return
+		}
+		content = string(encoded)
+	}
+
+	r.buf.WriteString("<div class=\"openapi-example\">\n")
+	r.buf.WriteString(r.opts.Markdown("```json\n" + content + "\n```\n"))
+	r.buf.WriteString("</div>\n")
+}
+
+func (r *renderer) markdown(source string) {
+	if strings.TrimSpace(source) == "" {
+		return
+	}
+
+	r.buf.WriteString(r.opts.Markdown(source))
+}
+
+func (r *renderer) warn(format string, a ...interface{}) {
+	warning := fmt.Sprintf(format, a...)
+
+	for _, w := range r.warnings {
+		if w == warning {
+			return
+		}
+	}
+
+	r.warnings = append(r.warnings, warning)
+}
+
+// schema resolves a schema reference.
+func (r *renderer) schema(schema *Schema) *Schema {
+	for depth := 0; schema != nil && schema.Ref != ""; depth++ {
+		name, ok := refName(schema.Ref, "schemas")
+		if !ok || depth > 32 {
+			r.warn("unresolved reference %q", schema.Ref)
+			return nil
+		}
+		resolved, exists := r.spec.Components.Schemas[name]
+		if !exists {
+			r.warn("unresolved reference %q", schema.Ref)
+			return nil
+		}
+		schema = resolved
+	}
+
+	return schema
+}
+
+func (r *renderer) parameter(param *Parameter) *Parameter {
+	if param == nil || param.Ref == "" {
+		return param
+	}
+
+	if name, ok := refName(param.Ref, "parameters"); ok && r.spec.Components.Parameters[name] != nil {
+		return r.spec.Components.Parameters[name]
+	}
+
+	r.warn("unresolved reference %q", param.Ref)
+	return nil
+}
+
+func (r *renderer) requestBody(body *RequestBody) *RequestBody {
+	if body == nil || body.Ref == "" {
+		return body
+	}
+
+	if name, ok := refName(body.Ref, "requestBodies"); ok && r.spec.Components.RequestBodies[name] != nil {
+		return r.spec.Components.RequestBodies[name]
+	}
+
+	r.warn("unresolved reference %q", body.Ref)
+	return nil
+}
+
+func (r *renderer) response(response *Response) *Response {
+	if response == nil || response.Ref == "" {
+		return response
+	}
+
+	if name, ok := refName(response.Ref, "responses"); ok && r.spec.Components.Responses[name] != nil {
+		return r.spec.Components.Responses[name]
+	}
+
+	r.warn("unresolved reference %q", response.Ref)
+	return nil
+}
+
+func (r *renderer) exampleRef(example *Example) *Example {
+	if example == nil || example.Ref == "" {
+		return example
+	}
+
+	if name, ok := refName(example.Ref, "examples"); ok && r.spec.Components.Examples[name] != nil {
+		return r.spec.Components.Examples[name]
+	}
+
+	r.warn("unresolved reference %q", example.Ref)
+	return nil
+}
+
+// schemaTypes returns the types of a schema, which is a list in OpenAPI 3.1.
+func schemaTypes(schema *Schema) []string {
+	switch t := schema.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := []string{}
+		for _, value := range t {
+			types = append(types, fmt.Sprint(value))
+		}
+		return types
+	}
+
+	return []string{}
+}
+
+func operationTitle(op *Operation) string {
+	if op.Summary != "" {
+		return op.Summary
+	}
+
+	return strings.ToUpper(op.Method) + " " + op.Path
+}
+
+func operationAnchor(op *Operation) string {
+	if op.OperationID != "" {
+		return "op-" + slug(op.OperationID)
+	}
+
+	return "op-" + slug(op.Method+" "+op.Path)
+}
+
+func slug(text string) string {
+	return strings.Trim(slugRegExp.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+
+	return line
+}
+
+func copySeen(seen map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(seen)+1)
+	for name := range seen {
+		copied[name] = true
+	}
+
+	return copied
+}
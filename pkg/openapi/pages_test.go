@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const cyclicSpec = `openapi: 3.0.0
+info:
+  title: Pets
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      responses:
+        "200":
+          description: A pet
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+components:
+  schemas:
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Animal"
+      properties:
+        name:
+          type: string
+    Animal:
+      allOf:
+        - $ref: "#/components/schemas/Pet"
+      properties:
+        legs:
+          type: integer
+`
+
+func TestPagesWithCyclicAllOf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "openapi.yaml")
+	if err := os.WriteFile(path, []byte(cyclicSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	pages, warnings, err := spec.Pages(Options{
+		Markdown: func(source string) string { return source },
+		Link:     func(slug string) string { return slug + ".html" },
+	})
+	if err != nil {
+		t.Fatalf("pages: %v", err)
+	}
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	html := ""
+	for _, page := range pages {
+		html += page.HTML
+	}
+
+	for _, want := range []string{"<code>name</code>", "<code>legs</code>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("pages do not have the property %s", want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lukeshay/gocden/pkg/data"
+	"gopkg.in/yaml.v2"
+)
+
+// Spec is an OpenAPI 3 specification. Only the parts that are shown on the
+// reference pages are read.
+type Spec struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Servers    []Server             `json:"servers"`
+	Tags       []Tag                `json:"tags"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type PathItem struct {
+	Summary     string       `json:"summary"`
+	Description string       `json:"description"`
+	Parameters  []*Parameter `json:"parameters"`
+	Get         *Operation   `json:"get"`
+	Put         *Operation   `json:"put"`
+	Post        *Operation   `json:"post"`
+	Delete      *Operation   `json:"delete"`
+	Options     *Operation   `json:"options"`
+	Head        *Operation   `json:"head"`
+	Patch       *Operation   `json:"patch"`
+	Trace       *Operation   `json:"trace"`
+}
+
+type Operation struct {
+	Tags        []string             `json:"tags"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+	Deprecated  bool                 `json:"deprecated"`
+
+	// Method and Path are set from the path item the operation is in.
+	Method string `json:"-"`
+	Path   string `json:"-"`
+}
+
+type Parameter struct {
+	Ref         string      `json:"$ref"`
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description"`
+	Required    bool        `json:"required"`
+	Deprecated  bool        `json:"deprecated"`
+	Schema      *Schema     `json:"schema"`
+	Example     interface{} `json:"example"`
+}
+
+type RequestBody struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema             `json:"schema"`
+	Example  interface{}         `json:"example"`
+	Examples map[string]*Example `json:"examples"`
+}
+
+type Example struct {
+	Ref     string      `json:"$ref"`
+	Summary string      `json:"summary"`
+	Value   interface{} `json:"value"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 interface{}        `json:"type"`
+	Format               string             `json:"format"`
+	Title                string             `json:"title"`
+	Description          string             `json:"description"`
+	Enum                 []interface{}      `json:"enum"`
+	Default              interface{}        `json:"default"`
+	Example              interface{}        `json:"example"`
+	Nullable             bool               `json:"nullable"`
+	ReadOnly             bool               `json:"readOnly"`
+	WriteOnly            bool               `json:"writeOnly"`
+	Deprecated           bool               `json:"deprecated"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	AllOf                []*Schema          `json:"allOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+}
+
+type Components struct {
+	Schemas       map[string]*Schema      `json:"schemas"`
+	Parameters    map[string]*Parameter   `json:"parameters"`
+	RequestBodies map[string]*RequestBody `json:"requestBodies"`
+	Responses     map[string]*Response    `json:"responses"`
+	Examples      map[string]*Example     `json:"examples"`
+}
+
+// Load reads a YAML or JSON specification.
+func Load(path string) (*Spec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(content, &value)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &value)
+	default:
+		return nil, fmt.Errorf("%s is not a YAML or JSON file", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+	}
+
+	// the YAML is converted to JSON, so both formats are decoded the same way
+	// and YAML keys such as response codes become strings
+	normalized, err := json.Marshal(data.Normalize(value))
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+	}
+
+	spec := &Spec{}
+	if err := json.Unmarshal(normalized, spec); err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %v", path, err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 specification", path)
+	}
+
+	return spec, nil
+}
+
+// Operations returns the operations of the specification, sorted by path and
+// then by method.
+func (s *Spec) Operations() []*Operation {
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	operations := []*Operation{}
+
+	for _, path := range paths {
+		item := s.Paths[path]
+		if item == nil {
+			continue
+		}
+
+		methods := []struct {
+			name      string
+			operation *Operation
+		}{
+			{"get", item.Get},
+			{"put", item.Put},
+			{"post", item.Post},
+			{"delete", item.Delete},
+			{"options", item.Options},
+			{"head", item.Head},
+			{"patch", item.Patch},
+			{"trace", item.Trace},
+		}
+
+		for _, method := range methods {
+			if method.operation == nil {
+				continue
+			}
+
+			op := *method.operation
+			op.Parameters = append([]*Parameter{}, op.Parameters...)
+			op.Method = method.name
+			op.Path = path
+
+			// parameters of the path apply to all of its operations, unless
+			// the operation overrides them
+			for _, param := range item.Parameters {
+				overridden := false
+				for _, opParam := range op.Parameters {
+					if param.Ref != "" && param.Ref == opParam.Ref || param.Ref == "" && param.Name == opParam.Name && param.In == opParam.In {
+						overridden = true
+					}
+				}
+				if !overridden {
+					op.Parameters = append(op.Parameters, param)
+				}
+			}
+
+			operations = append(operations, &op)
+		}
+	}
+
+	return operations
+}
+
+// refName returns the name of the component a local reference such as
+// #/components/schemas/Pet points to.
+func refName(ref string, kind string) (string, bool) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", false
+	}
+
+	return strings.ReplaceAll(strings.ReplaceAll(ref[len(prefix):], "~1", "/"), "~0", "~"), true
+}
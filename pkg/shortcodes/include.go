@@ -0,0 +1,200 @@
+package shortcodes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	includeRegExp          = regexp.MustCompile(`(?m)^([ \t]*)<!--\s*include:\s*(.+?)\s*-->[ \t]*$`)
+	includeShortcodeRegExp = regexp.MustCompile(`\{\{<\s*include\s+(?:"([^"]*)"|([^\s"/>]+))\s*/?>\}\}`)
+)
+
+// Inclusion is markdown with its includes inlined. Lines are the locations of
+// its lines in the file and the included files.
+type Inclusion struct {
+	Markdown     []byte
+	Lines        Lines
+	Dependencies []string
+}
+
+type includeDirective struct {
+	start  int
+	end    int
+	indent string
+	target string
+}
+
+// Include inlines the files included by the markdown file with
+//
+//	<!-- include: _partials/install.md -->
+//
+// or {{< include "_partials/install.md" >}}. Paths are relative to the file
+// and then to srcDir, and must be inside rootDir. Included files can include
+// other files, but not the files that include them.
+func Include(source []byte, file string, srcDir string, rootDir string) (*Inclusion, error) {
+	inc := &Inclusion{}
+
+	content, lines, err := include(inc, string(source), file, "", srcDir, rootDir, []string{file})
+	if err != nil {
+		return nil, err
+	}
+	inc.Markdown = []byte(content)
+	inc.Lines = lines
+
+	return inc, nil
+}
+
+// include inlines the includes of the source of file. errorFile is the file
+// errors are reported in, and the file of the locations of its lines, which
+// is empty for the page itself.
+func include(inc *Inclusion, source string, file string, errorFile string, srcDir string, rootDir string, stack []string) (string, Lines, error) {
+	code := codeRanges(source)
+	directives := []includeDirective{}
+
+	for _, m := range includeRegExp.FindAllStringSubmatchIndex(source, -1) {
+		if _, ok := inRanges(m[0], code); ok {
+			continue
+		}
+		directives = append(directives, includeDirective{m[0], m[1], source[m[2]:m[3]], source[m[4]:m[5]]})
+	}
+
+	for _, m := range includeShortcodeRegExp.FindAllStringSubmatchIndex(source, -1) {
+		if _, ok := inRanges(m[0], code); ok {
+			continue
+		}
+		target := ""
+		if m[2] >= 0 {
+			target = source[m[2]:m[3]]
+		} else {
+			target = source[m[4]:m[5]]
+		}
+		directives = append(directives, includeDirective{m[0], m[1], "", target})
+	}
+
+	sort.Slice(directives, func(i, j int) bool {
+		return directives[i].start < directives[j].start
+	})
+
+	out := newLineWriter()
+	pos := 0
+
+	for _, d := range directives {
+		out.copy(source[pos:d.start], errorFile, 1+strings.Count(source[:pos], "\n"))
+		pos = d.end
+
+		line := 1 + strings.Count(source[:d.start], "\n")
+
+		path, err := resolveInclude(d.target, file, srcDir, rootDir)
+		if err != nil {
+			return "", nil, &Error{File: errorFile, Line: line, Err: fmt.Errorf("could not include %s: %v", d.target, err)}
+		}
+
+		for i, f := range stack {
+			if f != path {
+				continue
+			}
+			cycle := []string{}
+			for _, c := range append(stack[i:], path) {
+				if rel, err := filepath.Rel(rootDir, c); err == nil {
+					c = rel
+				}
+				cycle = append(cycle, c)
+			}
+			return "", nil, &Error{File: errorFile, Line: line, Err: fmt.Errorf("include cycle: %s", strings.Join(cycle, " -> "))}
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", nil, &Error{File: errorFile, Line: line, Err: fmt.Errorf("could not include %s: %v", d.target, err)}
+		}
+
+		inc.Dependencies = append(inc.Dependencies, path)
+
+		// the lines of the file are after its frontmatter
+		body := stripFrontmatter(string(content))
+		frontmatterLines := strings.Count(string(content[:len(content)-len(body)]), "\n")
+
+		included, includedLines, err := include(inc, body, path, path, srcDir, rootDir, append(stack[:len(stack):len(stack)], path))
+		if err != nil {
+			var includeErr *Error
+			if errors.As(err, &includeErr) && includeErr.File == path {
+				includeErr.Line += frontmatterLines
+			}
+			return "", nil, err
+		}
+
+		for i := range includedLines {
+			if includedLines[i].File == path {
+				includedLines[i].Line += frontmatterLines
+			}
+		}
+
+		included = strings.TrimRight(included, "\n")
+
+		// an indented include, such as one in a list item, keeps the indent
+		if d.indent != "" {
+			lines := strings.Split(included, "\n")
+			for i, l := range lines {
+				if l != "" {
+					lines[i] = d.indent + l
+				}
+			}
+			included = strings.Join(lines, "\n")
+		}
+
+		out.writeLines(included, includedLines)
+	}
+
+	out.copy(source[pos:], errorFile, 1+strings.Count(source[:pos], "\n"))
+
+	return out.String(), out.lines, nil
+}
+
+// resolveInclude returns the first of the paths of the target, relative to
+// the file and then to srcDir, that is a file inside rootDir. Paths outside of
+// rootDir are skipped.
+func resolveInclude(target string, file string, srcDir string, rootDir string) (string, error) {
+	candidates := []string{filepath.Join(srcDir, target)}
+	if !strings.HasPrefix(target, "/") {
+		candidates = append([]string{filepath.Join(filepath.Dir(file), target)}, candidates...)
+	}
+
+	inside := false
+
+	for _, candidate := range candidates {
+		if rel, err := filepath.Rel(rootDir, candidate); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		inside = true
+
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	if !inside {
+		return "", fmt.Errorf("file is outside of %s", rootDir)
+	}
+
+	return "", errors.New("file does not exist")
+}
+
+// stripFrontmatter removes YAML frontmatter, so pages can be included too.
+func stripFrontmatter(content string) string {
+	if !strings.HasPrefix(content, "---\n") {
+		return content
+	}
+
+	end := strings.Index(content[4:], "\n---\n")
+	if end < 0 {
+		return content
+	}
+
+	return content[4+end+len("\n---\n"):]
+}
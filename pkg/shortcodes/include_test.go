@@ -0,0 +1,59 @@
+package shortcodes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIncludeSkipsCandidatesOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "site")
+	srcDir := filepath.Join(root, "docs")
+
+	writeFiles(t, dir, map[string]string{
+		// the shared file is in the root, so ../note.md relative to it is
+		// outside of the root, while relative to docs it is site/note.md
+		"site/shared.md": "<!-- include: ../note.md -->\n",
+		"site/note.md":   "inside\n",
+		"note.md":        "outside\n",
+	})
+
+	page := filepath.Join(srcDir, "index.md")
+	inc, err := Include([]byte("<!-- include: ../shared.md -->\n"), page, srcDir, root)
+	if err != nil {
+		t.Fatalf("include: %v", err)
+	}
+
+	if got := strings.TrimSpace(string(inc.Markdown)); got != "inside" {
+		t.Errorf("included %q, want %q", got, "inside")
+	}
+}
+
+func TestIncludeOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "site")
+	srcDir := filepath.Join(root, "docs")
+
+	writeFiles(t, dir, map[string]string{"secret.md": "secret\n"})
+
+	_, err := Include([]byte("text\n\n<!-- include: ../../secret.md -->\n"), filepath.Join(srcDir, "index.md"), srcDir, root)
+	if err == nil || !strings.Contains(err.Error(), "line 3: could not include ../../secret.md: file is outside of") {
+		t.Errorf("include outside of the root error = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package shortcodes
+
+import "strings"
+
+// Location is a line of a file. File is empty for the file that is expanded.
+type Location struct {
+	File string
+	Line int
+}
+
+// Lines are the locations that the lines of expanded markdown come from, the
+// first one is the location of line 1. Lines of includes are in the included
+// files, and the lines of shortcodes are the line of their tag.
+type Lines []Location
+
+// At returns the location that a line comes from, or the line itself when it
+// is not known.
+func (l Lines) At(line int) Location {
+	if line < 1 || line > len(l) {
+		return Location{Line: line}
+	}
+
+	return l[line-1]
+}
+
+// lineWriter builds expanded markdown and the locations of its lines. A line
+// is located where its first text comes from.
+type lineWriter struct {
+	out       strings.Builder
+	lines     Lines
+	lineStart bool
+}
+
+func newLineWriter() *lineWriter {
+	return &lineWriter{lineStart: true}
+}
+
+// copy writes text of the file that starts on the line, such as the text
+// between two shortcodes.
+func (w *lineWriter) copy(text string, file string, line int) {
+	w.write(text, func(i int) Location {
+		return Location{File: file, Line: line + i}
+	})
+}
+
+// insert writes text that all comes from one location, such as the output of
+// a shortcode.
+func (w *lineWriter) insert(text string, loc Location) {
+	w.write(text, func(int) Location {
+		return loc
+	})
+}
+
+// writeLines writes text whose lines come from the locations.
+func (w *lineWriter) writeLines(text string, lines Lines) {
+	w.write(text, func(i int) Location {
+		return lines.At(i + 1)
+	})
+}
+
+// write writes the text, with the location of its line i, starting at 0,
+// given by at.
+func (w *lineWriter) write(text string, at func(i int) Location) {
+	for i := 0; text != ""; i++ {
+		if w.lineStart {
+			w.lines = append(w.lines, at(i))
+			w.lineStart = false
+		}
+
+		end := strings.IndexByte(text, '\n')
+		if end < 0 {
+			w.out.WriteString(text)
+			return
+		}
+
+		w.out.WriteString(text[:end+1])
+		text = text[end+1:]
+		w.lineStart = true
+	}
+}
+
+func (w *lineWriter) String() string {
+	return w.out.String()
+}
@@ -0,0 +1,475 @@
+package shortcodes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/lukeshay/gocden/pkg/assets"
+	"github.com/lukeshay/gocden/pkg/data"
+)
+
+// Dir is the directory next to the config that holds the user's shortcodes.
+const Dir = "shortcodes"
+
+var (
+	nameRegExp  = regexp.MustCompile(`^/?\s*([A-Za-z][\w-]*)`)
+	fenceRegExp = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+)
+
+// Error is an error in a shortcode or include with the line it is on. File is
+// set when the error is in an included file rather than the page.
+type Error struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Context is the data a shortcode template is executed with. Positional
+// arguments are stored in Args by their index and Data is the site data.
+type Context struct {
+	Name  string
+	Args  map[string]string
+	Inner string
+	Data  map[string]interface{}
+}
+
+// Get returns the argument with the name or index, or an empty string.
+func (c *Context) Get(key interface{}) string {
+	return c.Args[fmt.Sprint(key)]
+}
+
+// Lookup returns the site data at a dotted path such as compat.go.
+func (c *Context) Lookup(path string) interface{} {
+	return data.Lookup(c.Data, path)
+}
+
+var funcs = map[string]interface{}{
+	"split": strings.Split,
+	"keys": func(value interface{}) []string {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	},
+}
+
+type shortcode struct {
+	path string
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Shortcodes are templates that are called from markdown with
+//
+//	{{< name arg="value" >}}
+//
+// or, to pass content to the template as .Inner,
+//
+//	{{< name >}}content{{< /name >}}
+//
+// Templates ending in .md produce markdown, templates ending in .html produce
+// HTML that is inserted into the page as is.
+type Shortcodes struct {
+	shortcodes map[string]*shortcode
+	data       map[string]interface{}
+}
+
+// Load reads the built-in shortcodes and the shortcodes in the directory,
+// which override built-ins with the same name. The directory does not have to
+// exist. The site data is available to the templates as .Data.
+func Load(dir string, siteData map[string]interface{}) (*Shortcodes, error) {
+	s := &Shortcodes{shortcodes: map[string]*shortcode{}, data: siteData}
+
+	builtins, err := fs.ReadDir(assets.Assets, "templates/shortcodes")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range builtins {
+		content, err := assets.ReadTemplate(path.Join("shortcodes", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(entry.Name(), "", string(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		filePath := filepath.Join(dir, entry.Name())
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.add(entry.Name(), filePath, string(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Shortcodes) add(fileName string, filePath string, content string) error {
+	ext := filepath.Ext(fileName)
+	name := strings.TrimSuffix(fileName, ext)
+	sc := &shortcode{path: filePath}
+
+	var err error
+
+	switch ext {
+	case ".md":
+		sc.text, err = texttemplate.New(name).Funcs(funcs).Parse(content)
+	case ".html":
+		sc.html, err = htmltemplate.New(name).Funcs(funcs).Parse(content)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("Could not parse shortcode %s: %v", fileName, err)
+	}
+
+	s.shortcodes[name] = sc
+
+	return nil
+}
+
+// Expansion is markdown with its shortcodes expanded. HTML shortcodes are
+// replaced by placeholders in the markdown, Restore puts the HTML back into
+// the converted page. Lines are the lines of the source that the lines of the
+// markdown come from.
+type Expansion struct {
+	Markdown     []byte
+	Lines        Lines
+	Dependencies []string
+
+	fragments map[string]string
+}
+
+// Restore replaces the placeholders of HTML shortcodes in the converted page.
+func (e *Expansion) Restore(html string) string {
+	for placeholder, fragment := range e.fragments {
+		// placeholders in attributes, such as heading ids, are dropped
+		inTag := regexp.MustCompile(`<[^<>]*` + placeholder + `[^<>]*>`)
+		html = inTag.ReplaceAllStringFunc(html, func(tag string) string {
+			return strings.ReplaceAll(strings.ReplaceAll(tag, "-"+placeholder, ""), placeholder, "")
+		})
+
+		// a shortcode on its own line is not wrapped in a paragraph
+		html = strings.ReplaceAll(html, "<p>"+placeholder+"</p>", fragment)
+		html = strings.ReplaceAll(html, placeholder, fragment)
+	}
+
+	return html
+}
+
+// Expand expands the shortcodes in the markdown. Shortcodes in code are left
+// alone and {{</* name */>}} is written as {{< name >}}.
+func (s *Shortcodes) Expand(source []byte) (*Expansion, error) {
+	e := &Expansion{fragments: map[string]string{}}
+
+	content, lines, err := s.expand(e, string(source), 1)
+	if err != nil {
+		return nil, err
+	}
+	e.Markdown = []byte(content)
+	e.Lines = lines
+
+	return e, nil
+}
+
+func (s *Shortcodes) expand(e *Expansion, source string, firstLine int) (string, Lines, error) {
+	code := codeRanges(source)
+	out := newLineWriter()
+
+	pos := 0
+	for {
+		start := nextTag(source, pos, code)
+		if start < 0 {
+			out.copy(source[pos:], "", firstLine+strings.Count(source[:pos], "\n"))
+			return out.String(), out.lines, nil
+		}
+
+		out.copy(source[pos:start], "", firstLine+strings.Count(source[:pos], "\n"))
+		line := firstLine + strings.Count(source[:start], "\n")
+
+		end := strings.Index(source[start:], ">}}")
+		if end < 0 {
+			return "", nil, &Error{Line: line, Err: errors.New("shortcode is not closed with >}}")}
+		}
+		end += start + len(">}}")
+
+		tag := strings.TrimSpace(source[start+len("{{<") : end-len(">}}")])
+
+		if strings.HasPrefix(tag, "/*") && strings.HasSuffix(tag, "*/") {
+			out.copy("{{< "+strings.TrimSpace(tag[2:len(tag)-2])+" >}}", "", line)
+			pos = end
+			continue
+		}
+
+		if strings.HasPrefix(tag, "/") {
+			return "", nil, &Error{Line: line, Err: fmt.Errorf("unexpected closing shortcode %q", tag)}
+		}
+
+		match := nameRegExp.FindStringSubmatch(tag)
+		if match == nil {
+			return "", nil, &Error{Line: line, Err: fmt.Errorf("invalid shortcode %q", tag)}
+		}
+
+		name := match[1]
+		sc, ok := s.shortcodes[name]
+		if !ok {
+			return "", nil, &Error{Line: line, Err: fmt.Errorf("unknown shortcode %q", name)}
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		args, err := parseArgs(strings.TrimSuffix(tag[len(match[0]):], "/"))
+		if err != nil {
+			return "", nil, &Error{Line: line, Err: fmt.Errorf("shortcode %s: %v", name, err)}
+		}
+
+		ctx := &Context{Name: name, Args: args, Data: s.data}
+
+		pos = end
+		if !selfClosing {
+			innerEnd, closeEnd := closingTag(source, name, end, code)
+			if innerEnd >= 0 {
+				innerLine := firstLine + strings.Count(source[:end], "\n")
+				inner, _, err := s.expand(e, source[end:innerEnd], innerLine)
+				if err != nil {
+					return "", nil, err
+				}
+				ctx.Inner = strings.Trim(inner, "\n")
+				pos = closeEnd
+			}
+		}
+
+		if sc.path != "" {
+			e.Dependencies = append(e.Dependencies, sc.path)
+		}
+
+		var buf bytes.Buffer
+		if sc.text != nil {
+			err = sc.text.Execute(&buf, ctx)
+		} else {
+			err = sc.html.Execute(&buf, ctx)
+		}
+		if err != nil {
+			return "", nil, &Error{Line: line, Err: fmt.Errorf("shortcode %s: %v", name, err)}
+		}
+
+		// the trailing newline of the template file is not part of the output
+		result := strings.TrimRight(buf.String(), "\n")
+
+		if sc.text != nil {
+			out.insert(result, Location{Line: line})
+		} else {
+			placeholder := fmt.Sprintf("gocdenshortcode%04dx", len(e.fragments))
+			e.fragments[placeholder] = result
+			out.insert(placeholder, Location{Line: line})
+		}
+	}
+}
+
+// nextTag returns the start of the next shortcode that is not in code, or -1.
+func nextTag(source string, pos int, code [][2]int) int {
+	for {
+		i := strings.Index(source[pos:], "{{<")
+		if i < 0 {
+			return -1
+		}
+		i += pos
+
+		if r, ok := inRanges(i, code); ok {
+			pos = r[1]
+			continue
+		}
+
+		return i
+	}
+}
+
+// closingTag finds the {{< /name >}} that closes a shortcode opened before
+// pos. It returns the start and end of the closing tag, or -1.
+func closingTag(source string, name string, pos int, code [][2]int) (int, int) {
+	depth := 0
+
+	for {
+		start := nextTag(source, pos, code)
+		if start < 0 {
+			return -1, -1
+		}
+
+		end := strings.Index(source[start:], ">}}")
+		if end < 0 {
+			return -1, -1
+		}
+		end += start + len(">}}")
+
+		tag := strings.TrimSpace(source[start+len("{{<") : end-len(">}}")])
+		match := nameRegExp.FindStringSubmatch(tag)
+
+		switch {
+		case match == nil || match[1] != name || strings.HasPrefix(tag, "/*"):
+		case strings.HasPrefix(tag, "/"):
+			if depth == 0 {
+				return start, end
+			}
+			depth--
+		case !strings.HasSuffix(tag, "/"):
+			depth++
+		}
+
+		pos = end
+	}
+}
+
+func parseArgs(s string) (map[string]string, error) {
+	args := map[string]string{}
+	index := 0
+
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return args, nil
+		}
+
+		key := ""
+		if eq := strings.IndexByte(s, '='); eq > 0 && !strings.ContainsAny(s[:eq], " \t\n\"") {
+			key = s[:eq]
+			s = s[eq+1:]
+		}
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := 1
+			var b strings.Builder
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' && end+1 < len(s) {
+					end++
+				}
+				b.WriteByte(s[end])
+			}
+			if end >= len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			value = b.String()
+			s = s[end+1:]
+		} else {
+			end := strings.IndexAny(s, " \t\n")
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+
+		if key == "" {
+			key = fmt.Sprint(index)
+			index++
+		}
+		args[key] = value
+	}
+}
+
+// codeRanges returns the byte ranges of fenced code blocks and code spans.
+func codeRanges(source string) [][2]int {
+	ranges := [][2]int{}
+	fence := ""
+	fenceStart := 0
+
+	offset := 0
+	for _, line := range strings.SplitAfter(source, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				ranges = append(ranges, [2]int{fenceStart, offset})
+				fence = ""
+			}
+			continue
+		}
+
+		if match := fenceRegExp.FindStringSubmatch(line); match != nil {
+			fence = match[1]
+			fenceStart = lineStart
+			continue
+		}
+
+		for i := 0; i < len(line); {
+			if line[i] != '`' {
+				i++
+				continue
+			}
+
+			run := i
+			for i < len(line) && line[i] == '`' {
+				i++
+			}
+			ticks := line[run:i]
+
+			closing := strings.Index(line[i:], ticks)
+			if closing < 0 {
+				continue
+			}
+			ranges = append(ranges, [2]int{lineStart + run, lineStart + i + closing + len(ticks)})
+			i += closing + len(ticks)
+		}
+	}
+
+	if fence != "" {
+		ranges = append(ranges, [2]int{fenceStart, len(source)})
+	}
+
+	return ranges
+}
+
+func inRanges(pos int, ranges [][2]int) ([2]int, bool) {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return r, true
+		}
+	}
+
+	return [2]int{}, false
+}
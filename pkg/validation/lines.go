@@ -0,0 +1,237 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// Line returns the line of the key, such as build.src or openapi[0].spec, in
+// the content, which is in the format toml, yaml or json and starts at line
+// start of its file. Keys that are not in the content, such as missing
+// required keys, get the line of the nearest table they are in, or start for
+// top-level keys.
+func Line(content []byte, format string, start int, key string) int {
+	var lines map[string]int
+
+	switch format {
+	case "toml":
+		lines = tomlLines(content)
+	case "yaml":
+		lines = yamlLines(content)
+	case "json":
+		lines = jsonLines(content)
+	default:
+		return 0
+	}
+
+	for key != "" {
+		if line, ok := lines[key]; ok {
+			return line + start - 1
+		}
+
+		key = ParentKey(key)
+	}
+
+	return start
+}
+
+// ParentKey returns the key of the table or array the key is in, such as
+// openapi[0] for openapi[0].spec, or an empty string for top-level keys.
+func ParentKey(key string) string {
+	if idx := strings.LastIndexAny(key, ".["); idx >= 0 {
+		return key[:idx]
+	}
+
+	return ""
+}
+
+// tomlLines returns the lines of the keys and tables of the toml. Tables of
+// arrays are numbered in the order they appear.
+func tomlLines(content []byte) map[string]int {
+	lines := map[string]int{}
+	counts := map[string]int{}
+
+	p := unstable.Parser{}
+	p.Reset(content)
+
+	prefix := ""
+
+	for p.NextExpression() {
+		expr := p.Expression()
+
+		keys := []string{}
+		line := 0
+
+		it := expr.Key()
+		for it.Next() {
+			node := it.Node()
+			if line == 0 {
+				line = p.Shape(node.Raw).Start.Line
+			}
+			keys = append(keys, string(node.Data))
+		}
+
+		if len(keys) == 0 {
+			continue
+		}
+
+		key := strings.Join(keys, ".")
+
+		switch expr.Kind {
+		case unstable.Table:
+			prefix = key
+		case unstable.ArrayTable:
+			prefix = fmt.Sprintf("%s[%d]", key, counts[key])
+			counts[key]++
+		case unstable.KeyValue:
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			lines[key] = line
+			continue
+		default:
+			continue
+		}
+
+		lines[prefix] = line
+	}
+
+	return lines
+}
+
+var yamlKeyRegExp = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s"'#-][^:#]*?|-[^\s:#][^:#]*?)\s*:(\s|$)`)
+
+// yamlLines returns the lines of the keys of the yaml, following their
+// indentation. Items of sequences are numbered in the order they appear.
+func yamlLines(content []byte) map[string]int {
+	type entry struct {
+		indent int
+		key    string
+		item   bool
+		items  int
+	}
+
+	lines := map[string]int{}
+	stack := []*entry{}
+
+	join := func(key string) string {
+		if len(stack) == 0 {
+			return key
+		}
+		return stack[len(stack)-1].key + "." + key
+	}
+
+	for idx, text := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(text) - len(strings.TrimLeft(text, " "))
+
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			// sequences can be indented as much as the key they are in
+			for len(stack) > 0 && (stack[len(stack)-1].indent > indent || stack[len(stack)-1].indent == indent && stack[len(stack)-1].item) {
+				stack = stack[:len(stack)-1]
+			}
+
+			key := ""
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				key = fmt.Sprintf("%s[%d]", parent.key, parent.items)
+				parent.items++
+			}
+
+			lines[key] = idx + 1
+			stack = append(stack, &entry{indent: indent, key: key, item: true})
+
+			// keys on the line of the dash are indented to after it
+			rest := text[indent+1:]
+			indent += 1 + len(rest) - len(strings.TrimLeft(rest, " "))
+			trimmed = strings.TrimSpace(rest)
+		} else {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+		}
+
+		match := yamlKeyRegExp.FindStringSubmatch(trimmed)
+		if match == nil {
+			continue
+		}
+
+		key := join(strings.Trim(match[1], `"'`))
+		lines[key] = idx + 1
+		stack = append(stack, &entry{indent: indent, key: key})
+	}
+
+	return lines
+}
+
+// jsonLines returns the lines of the keys of the json. Items of arrays are
+// numbered.
+func jsonLines(content []byte) map[string]int {
+	lines := map[string]int{}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+
+	lineAt := func() int {
+		return bytes.Count(content[:decoder.InputOffset()], []byte("\n")) + 1
+	}
+
+	join := func(prefix string, key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	var value func(key string) bool
+	value = func(key string) bool {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				name, err := decoder.Token()
+				if err != nil {
+					return false
+				}
+
+				child := join(key, fmt.Sprint(name))
+				lines[child] = lineAt()
+
+				if !value(child) {
+					return false
+				}
+			}
+		case json.Delim('['):
+			for idx := 0; decoder.More(); idx++ {
+				child := fmt.Sprintf("%s[%d]", key, idx)
+				lines[child] = lineAt()
+
+				if !value(child) {
+					return false
+				}
+			}
+		default:
+			return true
+		}
+
+		// the closing delimiter
+		_, err = decoder.Token()
+		return err == nil
+	}
+
+	value("")
+
+	return lines
+}
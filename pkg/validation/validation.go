@@ -1,39 +1,113 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New(validator.WithRequiredStructEnabled())
+// Error is an invalid key of a file, such as a frontmatter field of a page or
+// a key of the config. Line is 0 when the line is not known.
+type Error struct {
+	File    string
+	Line    int
+	Key     string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.File != "" && e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	} else if e.File != "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+
+	return e.Message
+}
+
+var mapKeyRegExp = regexp.MustCompile(`\[([^\]]*[^\]0-9][^\]]*)\]`)
 
-func ValidateAndPrint(message string, val interface{}) error {
-	if err := validate.Struct(val); err != nil {
-		fmt.Println(message)
+var (
+	validatorsMu sync.Mutex
+	validators   = map[string]*validator.Validate{}
+)
+
+// validatorFor returns a validator that names fields by the struct tag, so
+// errors use the keys of the file instead of the names of the fields.
+func validatorFor(tag string) *validator.Validate {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
 
-		if _, ok := err.(*validator.InvalidValidationError); ok {
-			fmt.Println(err)
+	if validate, ok := validators[tag]; ok {
+		return validate
+	}
 
-			return err
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
 		}
+		return name
+	})
+
+	validators[tag] = validate
+
+	return validate
+}
 
-		for _, err := range err.(validator.ValidationErrors) {
-			fmt.Println(err.Namespace())
-			fmt.Println(err.Field())
-			fmt.Println(err.StructNamespace())
-			fmt.Println(err.StructField())
-			fmt.Println(err.Tag())
-			fmt.Println(err.ActualTag())
-			fmt.Println(err.Kind())
-			fmt.Println(err.Type())
-			fmt.Println(err.Value())
-			fmt.Println(err.Param())
-			fmt.Println()
+// Validate validates the struct and returns an error for each invalid field.
+// Keys are named by the struct tag, such as toml or yaml, and kind is what
+// they are called in messages, such as frontmatter field. The File and Line
+// of the errors are left for the caller to set.
+func Validate(val interface{}, tag string, kind string) []*Error {
+	err := validatorFor(tag).Struct(val)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []*Error{{Message: err.Error()}}
+	}
+
+	result := []*Error{}
+
+	for _, fieldErr := range fieldErrs {
+		// the namespace starts with the name of the struct, and has the keys
+		// of maps in brackets like the indexes of arrays
+		_, key, _ := strings.Cut(fieldErr.Namespace(), ".")
+		key = mapKeyRegExp.ReplaceAllString(key, ".$1")
+
+		result = append(result, &Error{
+			Key:     key,
+			Message: message(fieldErr, kind, key),
+		})
+	}
+
+	return result
+}
+
+func message(fieldErr validator.FieldError, kind string, key string) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return fmt.Sprintf("%s %q is required", kind, key)
+	case "oneof":
+		options := strings.Fields(fieldErr.Param())
+		for i, option := range options {
+			options[i] = fmt.Sprintf("%q", option)
 		}
+		return fmt.Sprintf("%s %q must be one of %s, not %q", kind, key, strings.Join(options, ", "), fmt.Sprint(fieldErr.Value()))
+	}
 
-		return err
+	if fieldErr.Param() != "" {
+		return fmt.Sprintf("%s %q does not satisfy %s=%s", kind, key, fieldErr.Tag(), fieldErr.Param())
 	}
 
-	return nil
+	return fmt.Sprintf("%s %q does not satisfy %s", kind, key, fieldErr.Tag())
 }